@@ -6,6 +6,7 @@ require (
 	github.com/Ak-Army/config v1.0.1
 	github.com/Ak-Army/timer v1.2.0
 	github.com/Ak-Army/xlog v1.4.1
+	github.com/godbus/dbus/v5 v5.2.2
 	golang.org/x/oauth2 v0.35.0
 	google.golang.org/api v0.269.0
 )
@@ -4,11 +4,14 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
-	"os"
+	"io"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/internal/state"
 )
 
 // Header i3  header
@@ -20,18 +23,25 @@ type header struct {
 }
 
 type Bar struct {
+	mu            sync.Mutex
 	blocks        []Block
 	log           xlog.Logger
 	updateChannel chan UpdateChannelMsg
 	stop          chan bool
+	in            io.Reader
+	out           io.Writer
+	closeOnce     sync.Once
+	// the state store of the configuration of the bar
+	states *state.Store
 }
 
 type ClickMessage struct {
-	Name     string `json:"name,omitempty"`
-	Instance string `json:"instance,omitempty"`
-	Button   int    `json:"button"`
-	X        int    `json:"x"`
-	Y        int    `json:"y"`
+	Name      string   `json:"name,omitempty"`
+	Instance  string   `json:"instance,omitempty"`
+	Button    int      `json:"button"`
+	Modifiers []string `json:"modifiers,omitempty"`
+	X         int      `json:"x"`
+	Y         int      `json:"y"`
 }
 
 func (cm *ClickMessage) isMatch(block Block) bool {
@@ -46,8 +56,8 @@ func (b *Bar) Start() {
 		// ContinueSignal: syscall.SIGCONT,
 	}
 	headerJSON, _ := json.Marshal(header)
-	fmt.Println(string(headerJSON))
-	fmt.Println("[[]")
+	fmt.Fprintln(b.out, string(headerJSON))
+	fmt.Fprintln(b.out, "[[]")
 	b.ReStart()
 }
 
@@ -56,18 +66,94 @@ func (b *Bar) ReStart() {
 	go b.update()
 	go b.printItems()
 	go b.handleClick()
+	go b.saveStates()
 	<-b.stop
 }
 
+// Stop stops the bar and closes its modules.
 func (b *Bar) Stop() {
 	close(b.stop)
+	b.closeModules()
+}
+
+func (b *Bar) closeModules() {
+	b.closeOnce.Do(func() {
+		b.mu.Lock()
+		blocks := make([]Block, len(b.blocks))
+		copy(blocks, b.blocks)
+		b.mu.Unlock()
+		for _, block := range blocks {
+			if closer, ok := block.module.(Closer); ok {
+				if err := closer.Close(); err != nil {
+					b.log.Warnf("Close %s: %s", block.Info.Instance, err)
+				}
+			}
+		}
+	})
+}
+
+// RefreshSignal refreshes the blocks waiting for SIGRTMIN+signal.
+func (b *Bar) RefreshSignal(signal int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, block := range b.blocks {
+		if block.Signal == signal {
+			block.Refresh()
+		}
+	}
+}
+
+// RunAction runs a named action on the block with the given instance, or on
+// the first block offering it. A Carousel offers the actions of its modules
+// too, the action must not run twice.
+func (b *Bar) RunAction(name string, instance string) error {
+	b.mu.Lock()
+	blocks := make([]Block, len(b.blocks))
+	copy(blocks, b.blocks)
+	b.mu.Unlock()
+	for i, block := range blocks {
+		if (instance != "" && block.Info.Instance != instance) || !block.hasAction(name) {
+			continue
+		}
+		info, err := block.RunAction(name)
+		if err != nil {
+			b.log.Warnf("Action %s on %s: %s", name, block.Info.Instance, err)
+		}
+		b.setInfo(i, info)
+		return err
+	}
+	return fmt.Errorf("action not found: `%s`", name)
+}
+
+// Actions returns the named actions by block instance.
+func (b *Bar) Actions() map[string][]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	actions := map[string][]string{}
+	for _, block := range b.blocks {
+		if a := block.Actions(); len(a) > 0 {
+			actions[block.Info.Instance] = a
+		}
+	}
+	return actions
+}
+
+func (b *Bar) setInfo(i int, info *BlockInfo) {
+	if info == nil {
+		return
+	}
+	b.mu.Lock()
+	b.blocks[i].Info = *info
+	b.mu.Unlock()
+	b.Print()
 }
 
 func (b *Bar) Print() (minInterval int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	var infoArray []string
 	for _, item := range b.blocks {
-		item.Info.FullText = item.Label + " " + item.Info.FullText
-		item.Info.ShortText = item.Label + " " + item.Info.ShortText
+		item.Info.FullText, item.Info.ShortText = item.render()
 
 		info, err := json.Marshal(item.Info)
 		if err != nil {
@@ -79,7 +165,7 @@ func (b *Bar) Print() (minInterval int64) {
 			minInterval = item.Interval
 		}
 	}
-	fmt.Println(",[", strings.Join(infoArray, ",\n"), "]")
+	fmt.Fprintln(b.out, ",[", strings.Join(infoArray, ",\n"), "]")
 
 	return minInterval
 }
@@ -91,20 +177,29 @@ func (b *Bar) update() {
 			b.log.Debug("Stop update")
 			return
 		case m := <-b.updateChannel:
+			b.mu.Lock()
 			b.blocks[m.ID].Info = m.Info
+			b.mu.Unlock()
+			if m.Refresh {
+				b.Print()
+			}
 		}
 	}
 }
 
 func (b *Bar) handleClick() {
+	bio := bufio.NewReader(b.in)
 	for {
 		select {
 		case <-b.stop:
 			b.log.Debug("Stop handleClick")
 			return
 		default:
-			bio := bufio.NewReader(os.Stdin)
 			line, _, err := bio.ReadLine()
+			if err == io.EOF {
+				b.log.Debug("Stop handleClick: input closed")
+				return
+			}
 			if err != nil {
 				continue
 			}
@@ -121,17 +216,18 @@ func (b *Bar) handleClick() {
 			err = json.Unmarshal(line, &clickMessage)
 			if err == nil {
 				b.log.Debugf("Click: line: %s, cm:%+v", string(line), clickMessage)
-				for i, block := range b.blocks {
+				b.mu.Lock()
+				blocks := make([]Block, len(b.blocks))
+				copy(blocks, b.blocks)
+				b.mu.Unlock()
+				for i, block := range blocks {
 					if clickMessage.isMatch(block) {
 						b.log.Debug("Click: handled")
 						info, err := block.HandleClick(clickMessage)
 						if err != nil {
 							b.log.Debug("Click: error: ", err.Error())
 						}
-						if info != nil {
-							b.blocks[i].Info = *info
-							b.Print()
-						}
+						b.setInfo(i, info)
 					}
 				}
 			}
@@ -148,9 +244,15 @@ func (b *Bar) printItems() {
 		default:
 			minInterval := b.Print()
 			if minInterval == 0 {
-				break
+				// only refreshes and clicks print from now on
+				<-b.stop
+				b.log.Debug("Stop printItems")
+				return
+			}
+			select {
+			case <-b.stop:
+			case <-clock.After(time.Duration(minInterval) * time.Second):
 			}
-			time.Sleep(time.Duration(minInterval) * time.Second)
 		}
 	}
 }
@@ -27,36 +27,55 @@ type BlockInfo struct {
 	BorderBottom        int         `config:"border_bottom" json:"border_bottom"`
 	BorderLeft          int         `config:"border_left" json:"border_left"`
 	BorderRight         int         `config:"border_right" json:"border_right"`
+	// Set by the module when the update failed, it is counted in the
+	// metrics and not sent to i3bar
+	Failed bool `config:"-" json:"-"`
 }
 
 // Block i3  item
 type Block struct {
+	// Stable identifier used as the i3bar instance, id_<index> when empty
+	ID         string          `config:"id" json:"id,omitempty"`
 	ModuleName string          `config:"module" json:"module"`
 	Label      string          `config:"label" json:"label"`
 	Interval   int64           `config:"interval" json:"interval"`
 	Info       BlockInfo       `config:"info" json:"info,omitempty"`
 	Config     json.RawMessage `config:"config" json:"config,omitempty"`
+	// Refresh the block on SIGRTMIN+Signal
+	Signal int `config:"signal" json:"signal,omitempty"`
+	// Click actions by button with modifiers, eg: "left", "ctrl+3"
+	OnClick map[string]ClickAction `config:"on_click" json:"on_click,omitempty"`
+	// Texts with {label}, {full_text} and {short_text} placeholders, the
+	// cycle_format click action switches between them
+	Formats    []string `config:"formats" json:"formats,omitempty"`
 	module     ModuleInterface
 	lastUpdate int64
+	refresh    chan struct{}
+	format     *formatState
 }
 
 type UpdateChannelMsg struct {
-	ID   int
-	Info BlockInfo
+	ID      int
+	Info    BlockInfo
+	Refresh bool
+}
+
+func (block *Block) instance(index int) string {
+	if block.ID != "" {
+		return block.ID
+	}
+	return fmt.Sprintf("id_%d", index)
 }
 
 func (block *Block) CreateModule(id int, log xlog.Logger) error {
-	block.Info.Instance = fmt.Sprintf("id_%d", id)
+	block.Info.Instance = block.instance(id)
 	if block.Info.Name == "" {
 		block.Info.Name = block.ModuleName
 	}
+	log = xlog.Copy(log)
+	log.SetField("block", block.Info.Instance)
 	var err error
-	if module, ok := moduleRegistry[block.ModuleName]; ok {
-		block.module = module()
-		err = block.module.InitModule(block.Config, log)
-	} else {
-		err = fmt.Errorf("module not found: `%s`", block.ModuleName)
-	}
+	block.module, err = NewModule(block.ModuleName, block.Config, log)
 	if err != nil {
 		block.Label = "ERR: " + err.Error()
 		block.Info = BlockInfo{
@@ -68,30 +87,86 @@ func (block *Block) CreateModule(id int, log xlog.Logger) error {
 		block.module = moduleRegistry["StaticText"]()
 		block.module.InitModule(block.Config, log)
 	}
+	if err == nil {
+		block.restoreState(log)
+	}
+	block.format = &formatState{}
+	if clickErr := block.parseOnClick(); clickErr != nil {
+		// rejected by Config.Validate already, the block still runs
+		log.Error(clickErr)
+	}
+	block.refresh = make(chan struct{}, 1)
+	if refresher, ok := block.module.(Refresher); ok {
+		refresher.SetRefresh(block.Refresh)
+	}
 	return err
 }
 
+// BlockInstance returns the instance of the block from the logger given to
+// InitModule, eg: to name the files of the module.
+func BlockInstance(log xlog.Logger) string {
+	instance, _ := log.GetFields()["block"].(string)
+	return instance
+}
+
+// Refresh updates the block as soon as possible.
+func (block Block) Refresh() {
+	select {
+	case block.refresh <- struct{}{}:
+	default:
+	}
+}
+
 func (block Block) Start(ID int, updateChannel chan<- UpdateChannelMsg) {
 	defer func() {
 		if r := recover(); r != nil {
+			metrics.incPanics(block)
 			xlog.Errorf("recovered: %s -> stackTrace: %s", r, debug.Stack())
 		}
 	}()
+	_, canRefresh := block.module.(Refresher)
+	refresh := false
 	for {
+		start := time.Now()
 		newInfo := block.module.UpdateInfo(block.Info)
+		metrics.observeUpdate(block, time.Since(start))
+		if newInfo.Failed {
+			metrics.incErrors(block)
+		}
 		m := UpdateChannelMsg{
-			ID:   ID,
-			Info: newInfo,
+			ID:      ID,
+			Info:    newInfo,
+			Refresh: refresh,
 		}
 		updateChannel <- m
-		block.lastUpdate = time.Now().Unix()
-		if block.Interval == 0 {
+		block.lastUpdate = clock.Now().Unix()
+		if block.Interval == 0 && !canRefresh && block.Signal == 0 {
 			break
 		}
-		time.Sleep(time.Duration(block.Interval) * time.Second)
+		var timeout <-chan time.Time
+		if block.Interval > 0 {
+			timeout = clock.After(time.Duration(block.Interval) * time.Second)
+		}
+		select {
+		case <-timeout:
+			refresh = false
+		case <-block.refresh:
+			refresh = true
+		}
 	}
 }
 
 func (block Block) HandleClick(cm ClickMessage) (*BlockInfo, error) {
-	return block.module.HandleClick(cm, block.Info)
+	metrics.incClicks(block)
+	var info *BlockInfo
+	var err error
+	if action, ok := block.OnClick[clickKey(cm.Button, cm.Modifiers)]; ok {
+		info, err = block.handleAction(action, block.Info)
+	} else {
+		info, err = block.module.HandleClick(cm, block.Info)
+	}
+	if err != nil {
+		metrics.incErrors(block)
+	}
+	return info, err
 }
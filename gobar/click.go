@@ -0,0 +1,212 @@
+package gobar
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ClickAction is what happens on a configured click of a block, the first
+// set field wins. The texts can contain the {full_text}, {short_text},
+// {name} and {instance} placeholders of the clicked block. In commands they
+// are replaced with the quoted $FULL_TEXT, $SHORT_TEXT, $NAME and $INSTANCE
+// environment variables, so the texts are never run by the shell.
+type ClickAction struct {
+	// Shell command started in the background
+	Command string `json:"command,omitempty"`
+	// URL opened in the browser
+	URL string `json:"url,omitempty"`
+	// Text copied to the clipboard
+	Copy string `json:"copy,omitempty"`
+	// Update the block immediately
+	Refresh bool `json:"refresh,omitempty"`
+	// Switch to the next entry of the block's formats
+	CycleFormat bool `json:"cycle_format,omitempty"`
+	// Named action of the module
+	Action string `json:"action,omitempty"`
+}
+
+// ActionHandler is implemented by the modules with named actions, eg:
+// "toggl.toggle". The actions can be bound to clicks or run over IPC.
+type ActionHandler interface {
+	Actions() []string
+	HandleAction(name string, info BlockInfo) (*BlockInfo, error)
+}
+
+var buttonNames = map[string]int{
+	"left":        1,
+	"middle":      2,
+	"right":       3,
+	"scroll_up":   4,
+	"scroll_down": 5,
+}
+
+var modifierNames = map[string]string{
+	"control": "ctrl",
+	"mod1":    "alt",
+	"mod4":    "super",
+}
+
+// lockModifiers are the NumLock and CapsLock states, they are not part of
+// the keys.
+var lockModifiers = map[string]bool{
+	"mod2": true,
+	"lock": true,
+}
+
+// clickKey returns the on_click key of a button with modifiers, eg:
+// "ctrl+shift+1".
+func clickKey(button int, modifiers []string) string {
+	var mods []string
+	for _, mod := range modifiers {
+		mod = strings.ToLower(mod)
+		if lockModifiers[mod] {
+			continue
+		}
+		if name, ok := modifierNames[mod]; ok {
+			mod = name
+		}
+		mods = append(mods, mod)
+	}
+	sort.Strings(mods)
+	return strings.Join(append(mods, strconv.Itoa(button)), "+")
+}
+
+// parseClickKey normalizes a configured key like "Shift+left" or "3".
+func parseClickKey(key string) (string, error) {
+	parts := strings.Split(key, "+")
+	button := strings.ToLower(parts[len(parts)-1])
+	n, ok := buttonNames[button]
+	if !ok {
+		var err error
+		if n, err = strconv.Atoi(button); err != nil {
+			return "", fmt.Errorf("invalid button in on_click: `%s`", key)
+		}
+	}
+	return clickKey(n, parts[:len(parts)-1]), nil
+}
+
+type formatState struct {
+	mu      sync.Mutex
+	current int
+}
+
+// parseOnClick normalizes the on_click keys, the invalid ones are dropped.
+func (block *Block) parseOnClick() error {
+	actions := make(map[string]ClickAction, len(block.OnClick))
+	var lastErr error
+	for key, action := range block.OnClick {
+		k, err := parseClickKey(key)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		actions[k] = action
+	}
+	block.OnClick = actions
+	return lastErr
+}
+
+func (block Block) handleAction(action ClickAction, info BlockInfo) (*BlockInfo, error) {
+	expand := strings.NewReplacer(
+		"{full_text}", info.FullText,
+		"{short_text}", info.ShortText,
+		"{name}", info.Name,
+		"{instance}", info.Instance,
+	).Replace
+	switch {
+	case action.Command != "":
+		command := strings.NewReplacer(
+			"{full_text}", `"$FULL_TEXT"`,
+			"{short_text}", `"$SHORT_TEXT"`,
+			"{name}", `"$NAME"`,
+			"{instance}", `"$INSTANCE"`,
+		).Replace(action.Command)
+		env := []string{
+			"FULL_TEXT=" + info.FullText,
+			"SHORT_TEXT=" + info.ShortText,
+			"NAME=" + info.Name,
+			"INSTANCE=" + info.Instance,
+		}
+		return showError(info, LaunchEnv(env, "sh", "-c", command))
+	case action.URL != "":
+		return showError(info, OpenURL(expand(action.URL)))
+	case action.Copy != "":
+		return showError(info, Copy(expand(action.Copy)))
+	case action.Refresh:
+		block.Refresh()
+		return nil, nil
+	case action.CycleFormat:
+		if len(block.Formats) == 0 {
+			return nil, errors.New("no formats configured")
+		}
+		block.format.mu.Lock()
+		block.format.current = (block.format.current + 1) % len(block.Formats)
+		block.format.mu.Unlock()
+		return &info, nil
+	case action.Action != "":
+		if !block.hasAction(action.Action) {
+			return nil, fmt.Errorf("action not found: `%s`", action.Action)
+		}
+		return block.module.(ActionHandler).HandleAction(action.Action, info)
+	}
+	return nil, errors.New("empty click action")
+}
+
+// Actions returns the named actions of the block's module.
+func (block Block) Actions() []string {
+	if handler, ok := block.module.(ActionHandler); ok {
+		return handler.Actions()
+	}
+	return nil
+}
+
+func (block Block) hasAction(name string) bool {
+	for _, action := range block.Actions() {
+		if action == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RunAction runs a named action of the block's module.
+func (block Block) RunAction(name string) (*BlockInfo, error) {
+	info, err := block.handleAction(ClickAction{Action: name}, block.Info)
+	if err != nil {
+		metrics.incErrors(block)
+	}
+	return info, err
+}
+
+// showError shows the error in the block until its next update.
+func showError(info BlockInfo, err error) (*BlockInfo, error) {
+	if err == nil {
+		return nil, nil
+	}
+	info.FullText = err.Error()
+	info.ShortText = err.Error()
+	info.TextColor = "#FF2222"
+	return &info, err
+}
+
+// render returns the texts of the block with the label or the current format.
+func (block Block) render() (string, string) {
+	if len(block.Formats) == 0 {
+		return block.Label + " " + block.Info.FullText, block.Label + " " + block.Info.ShortText
+	}
+	block.format.mu.Lock()
+	format := block.Formats[block.format.current%len(block.Formats)]
+	block.format.mu.Unlock()
+	expand := func(text string) string {
+		return strings.NewReplacer(
+			"{label}", block.Label,
+			"{full_text}", text,
+			"{short_text}", block.Info.ShortText,
+		).Replace(format)
+	}
+	return expand(block.Info.FullText), expand(block.Info.ShortText)
+}
@@ -0,0 +1,31 @@
+package gobar
+
+import (
+	"sync"
+
+	"github.com/Ak-Army/i3barfeeder/internal/clipboard"
+)
+
+var (
+	clipMu sync.Mutex
+	clip   = clipboard.New(clipboard.Config{})
+)
+
+func setupClipboard(config *clipboard.Config) {
+	if config == nil {
+		config = &clipboard.Config{}
+	}
+	newClip := clipboard.New(*config)
+	clipMu.Lock()
+	defer clipMu.Unlock()
+	clip = newClip
+}
+
+// Copy puts the text on the clipboard with the configured or detected
+// backend.
+func Copy(text string) error {
+	clipMu.Lock()
+	current := clip
+	clipMu.Unlock()
+	return current.Copy(text)
+}
@@ -0,0 +1,38 @@
+package gobar
+
+import "time"
+
+// Clock is the time source of the bar, it can be replaced in tests.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+var clock Clock = realClock{}
+
+func SetClock(c Clock) {
+	if c == nil {
+		c = realClock{}
+	}
+	clock = c
+}
+
+// Now returns the current time of the bar clock.
+func Now() time.Time {
+	return clock.Now()
+}
+
+// After waits for the duration on the bar clock.
+func After(d time.Duration) <-chan time.Time {
+	return clock.After(d)
+}
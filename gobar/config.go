@@ -2,8 +2,13 @@ package gobar
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
 	"reflect"
 	"runtime/debug"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,14 +16,24 @@ import (
 	"github.com/Ak-Army/config/backend"
 	"github.com/Ak-Army/config/backend/file"
 	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/internal/clipboard"
+	"github.com/Ak-Army/i3barfeeder/internal/launcher"
+	"github.com/Ak-Army/i3barfeeder/internal/notify"
+	"github.com/Ak-Army/i3barfeeder/internal/state"
 )
 
 var store *Store
 var defaults reflect.Value
 
 type Config struct {
-	Defaults *BlockInfo `config:"defaults"`
-	Blocks   []Block    `config:"blocks"`
+	Defaults      *BlockInfo        `config:"defaults"`
+	Blocks        []Block           `config:"blocks"`
+	Notifications *notify.Config    `config:"notifications"`
+	Metrics       *MetricsConfig    `config:"metrics"`
+	Clipboard     *clipboard.Config `config:"clipboard"`
+	Launcher      *launcher.Config  `config:"launcher"`
+	State         *state.Config     `config:"state"`
 }
 
 type Store struct {
@@ -37,12 +52,20 @@ func (c *Store) SetSnapshot(confInterface interface{}, err error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	conf := confInterface.(*Config)
+	if err == nil {
+		err = conf.Validate()
+	}
+	if err != nil && c.config != nil {
+		xlog.Errorf("Invalid configuration, keeping the previous one: %s", err)
+		return
+	}
 	c.config = conf
 	if c.bar != nil {
+		c.bar.SaveState()
 		newBar := c.config.createBar()
 		c.bar.Stop()
 		c.bar = newBar
-		c.bar.ReStart()
+		go c.bar.ReStart()
 	}
 	c.err = err
 }
@@ -65,20 +88,115 @@ func New(f string) (*Store, error) {
 				file.WithOption(backend.WithWatcher()),
 			),
 		)
-		if err != nil {
+		if store.err != nil {
 			return
 		}
-		store.err = loader.Load(store)
+		if err := loader.Load(store); err != nil {
+			store.err = err
+		}
 	})
+	if err == nil {
+		err = store.err
+	}
 	return store, err
 }
 
 func (c *Store) Start() {
+	c.mu.Lock()
 	c.bar = c.config.createBar()
-	c.bar.Start()
+	bar := c.bar
+	c.mu.Unlock()
+	bar.Start()
+}
+
+// RefreshSignal refreshes the blocks configured for SIGRTMIN+signal.
+func (c *Store) RefreshSignal(signal int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.bar != nil {
+		c.bar.RefreshSignal(signal)
+	}
+}
+
+// RunAction runs a named action, see Bar.RunAction.
+func (c *Store) RunAction(name string, instance string) error {
+	c.mu.RLock()
+	bar := c.bar
+	c.mu.RUnlock()
+	if bar == nil {
+		return errors.New("bar is not running")
+	}
+	return bar.RunAction(name, instance)
+}
+
+// Actions returns the named actions by block instance.
+func (c *Store) Actions() map[string][]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.bar == nil {
+		return nil
+	}
+	return c.bar.Actions()
+}
+
+// Notifications returns the recently sent notifications, see
+// Bar.Notifications.
+func (c *Store) Notifications() []notify.Notification {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.bar == nil {
+		return nil
+	}
+	return c.bar.Notifications()
+}
+
+// Close closes the modules of the running bar on exit.
+func (c *Store) Close() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.bar != nil {
+		c.bar.closeModules()
+	}
+}
+
+// SaveState writes the state of the modules, see Bar.SaveState.
+func (c *Store) SaveState() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.bar != nil {
+		c.bar.SaveState()
+	}
+}
+
+// Validate checks the block identifiers, they have to be unique and usable
+// as an IPC argument, and the on_click keys.
+func (c *Config) Validate() error {
+	seen := map[string]int{}
+	for i := range c.Blocks {
+		id := c.Blocks[i].instance(i)
+		if strings.ContainsAny(id, " \t\n") {
+			return fmt.Errorf("block %d: invalid id: `%s`", i, id)
+		}
+		if prev, ok := seen[id]; ok {
+			return fmt.Errorf("block %d: duplicated id `%s`, already used by block %d", i, id, prev)
+		}
+		seen[id] = i
+		for key := range c.Blocks[i].OnClick {
+			if _, err := parseClickKey(key); err != nil {
+				return fmt.Errorf("block %d: %s", i, err)
+			}
+		}
+	}
+	return nil
 }
 
 func (c *Config) createBar() *Bar {
+	return c.NewBar(os.Stdin, os.Stdout)
+}
+
+// NewBar creates the modules and starts updating them, the bar reads the
+// click events from in and writes the i3bar protocol to out.
+func (c *Config) NewBar(in io.Reader, out io.Writer) *Bar {
 	defer func() {
 		if err := recover(); err != nil {
 			xlog.Errorf("%+v %s", err, string(debug.Stack()))
@@ -87,23 +205,37 @@ func (c *Config) createBar() *Bar {
 	log := xlog.GetLogger()
 	updateChannel := make(chan UpdateChannelMsg)
 	xlog.Info(c.Defaults)
+	if c.Defaults == nil {
+		c.Defaults = &BlockInfo{}
+	}
 	defaults = reflect.ValueOf(c.Defaults).Elem()
+	setupNotifier(c.Notifications)
+	setupClipboard(c.Clipboard)
+	setupLauncher(c.Launcher)
+	states := setupState(c.State)
+	metrics.listen(c.Metrics)
 	for i := range c.Blocks {
 		mapDefaults(&c.Blocks[i].Info)
 		err := c.Blocks[i].CreateModule(i, log)
 		if err == nil {
 			go c.Blocks[i].Start(i, updateChannel)
 		} else {
+			metrics.incErrors(c.Blocks[i])
 			log.Error(err)
 		}
 	}
 
 	log.Infof("Bar items: %+v", c.Blocks)
-	return &Bar{
+	bar := &Bar{
 		blocks:        c.Blocks,
 		log:           log,
 		updateChannel: updateChannel,
+		in:            in,
+		out:           out,
+		states:        states,
 	}
+	metrics.setBar(bar)
+	return bar
 }
 
 func mapDefaults(blockInfo *BlockInfo) {
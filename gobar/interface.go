@@ -22,6 +22,31 @@ type ModuleInterface interface {
 	HandleClick(cm ClickMessage, info BlockInfo) (*BlockInfo, error)
 }
 
+// MetricsProvider is implemented by the modules exporting their values,
+// the keys are metric names without the i3barfeeder_ prefix.
+type MetricsProvider interface {
+	Metrics() map[string]float64
+}
+
+// Refresher is implemented by the modules which know when their block has to
+// be updated, calling refresh updates and prints the block immediately.
+type Refresher interface {
+	SetRefresh(refresh func())
+}
+
+// StateSaver is implemented by the modules keeping their state across
+// restarts, the state is saved periodically and on exit.
+type StateSaver interface {
+	SaveState() (json.RawMessage, error)
+	RestoreState(state json.RawMessage) error
+}
+
+// Closer is implemented by the modules running processes or goroutines,
+// Close is called when the bar stops on a reload or on exit.
+type Closer interface {
+	Close() error
+}
+
 type BlockMarkup string
 
 type BlockAlign string
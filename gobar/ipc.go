@@ -0,0 +1,160 @@
+package gobar
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Ak-Army/xlog"
+)
+
+// The IPC protocol is one line per connection:
+//
+//	<action> [instance]  runs the action of the instance or of the first block
+//	                     offering it, answers "ok" or "error: <reason>"
+//	list                 answers "<instance> <action>" lines
+//	notifications        answers "<time> <summary>[: <body>]" lines, oldest first
+const (
+	ipcList          = "list"
+	ipcNotifications = "notifications"
+)
+
+// DefaultSocket returns the path of the IPC socket, in a directory of the
+// user when XDG_RUNTIME_DIR is not set.
+func DefaultSocket() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("i3barfeeder-%d", os.Getuid()))
+	}
+	return filepath.Join(dir, "i3barfeeder.sock")
+}
+
+// socketDir creates the directory of the socket, an existing one has to be
+// owned by the user or by root and must not be writable by the others.
+func socketDir(dir string) error {
+	if err := os.Mkdir(dir, 0700); err == nil || !os.IsExist(err) {
+		return err
+	}
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("socket directory is not a directory: %s", dir)
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok && st.Uid != uint32(os.Getuid()) && st.Uid != 0 {
+		return fmt.Errorf("socket directory is owned by an other user: %s", dir)
+	}
+	if fi.Mode().Perm()&0022 != 0 && fi.Mode()&os.ModeSticky == 0 {
+		return fmt.Errorf("socket directory is writable by the others: %s", dir)
+	}
+	return nil
+}
+
+// ListenIPC serves the named actions of the bar on a unix socket until the
+// returned listener is closed.
+func (c *Store) ListenIPC(path string) (net.Listener, error) {
+	if err := socketDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("socket is in use: %s", path)
+	}
+	os.Remove(path)
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				xlog.Debug("Stop IPC: ", err)
+				return
+			}
+			go c.serveIPC(conn)
+		}
+	}()
+	return l, nil
+}
+
+func (c *Store) serveIPC(conn net.Conn) {
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 || len(fields) > 2 {
+		fmt.Fprintln(conn, "error: invalid request")
+		return
+	}
+	xlog.Debugf("IPC: %s", strings.TrimSpace(line))
+	if fields[0] == ipcList {
+		actions := c.Actions()
+		for _, instance := range sortedInstances(actions) {
+			for _, action := range actions[instance] {
+				fmt.Fprintln(conn, instance, action)
+			}
+		}
+		return
+	}
+	if fields[0] == ipcNotifications {
+		for _, n := range c.Notifications() {
+			line := n.Time.Format(time.RFC3339) + " " + n.Summary
+			if n.Body != "" {
+				line += ": " + strings.ReplaceAll(n.Body, "\n", " ")
+			}
+			fmt.Fprintln(conn, line)
+		}
+		return
+	}
+	var instance string
+	if len(fields) == 2 {
+		instance = fields[1]
+	}
+	if err := c.RunAction(fields[0], instance); err != nil {
+		fmt.Fprintln(conn, "error:", err)
+		return
+	}
+	fmt.Fprintln(conn, "ok")
+}
+
+func sortedInstances(actions map[string][]string) []string {
+	instances := make([]string, 0, len(actions))
+	for instance := range actions {
+		instances = append(instances, instance)
+	}
+	sort.Strings(instances)
+	return instances
+}
+
+// SendAction sends a request to a running bar and returns its answer.
+func SendAction(path string, request string) (string, error) {
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if _, err := fmt.Fprintln(conn, request); err != nil {
+		return "", err
+	}
+	answer, err := io.ReadAll(conn)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(string(answer), "error: ") {
+		return "", errors.New(strings.TrimSpace(strings.TrimPrefix(string(answer), "error: ")))
+	}
+	return string(answer), nil
+}
@@ -0,0 +1,47 @@
+package gobar
+
+import (
+	"sync"
+
+	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/internal/launcher"
+)
+
+var (
+	launchMu sync.Mutex
+	launch   = launcher.New(launcher.Config{}, xlog.GetLogger())
+)
+
+func setupLauncher(config *launcher.Config) {
+	if config == nil {
+		config = &launcher.Config{}
+	}
+	newLaunch := launcher.New(*config, xlog.GetLogger())
+	launchMu.Lock()
+	defer launchMu.Unlock()
+	launch = newLaunch
+}
+
+func currentLauncher() *launcher.Launcher {
+	launchMu.Lock()
+	defer launchMu.Unlock()
+	return launch
+}
+
+// OpenURL opens the URL with the configured handler of its scheme or in the
+// browser, without waiting for it.
+func OpenURL(url string) error {
+	return currentLauncher().Open(url)
+}
+
+// Launch starts a program in the background.
+func Launch(argv ...string) error {
+	return currentLauncher().Run(argv...)
+}
+
+// LaunchEnv starts a program in the background with extra environment
+// variables, eg: "NAME=value".
+func LaunchEnv(env []string, argv ...string) error {
+	return currentLauncher().RunEnv(env, argv...)
+}
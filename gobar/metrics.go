@@ -0,0 +1,176 @@
+package gobar
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ak-Army/xlog"
+)
+
+type MetricsConfig struct {
+	// Address of the HTTP listener, eg: 127.0.0.1:9101
+	Listen string `config:"listen" json:"listen"`
+}
+
+type blockMetrics struct {
+	module        string
+	updates       uint64
+	updateSeconds float64
+	lastUpdate    float64
+	errors        uint64
+	panics        uint64
+	clicks        uint64
+}
+
+type feederMetrics struct {
+	mu     sync.Mutex
+	blocks map[string]*blockMetrics
+	server *http.Server
+	bar    *Bar
+}
+
+var metrics = &feederMetrics{blocks: make(map[string]*blockMetrics)}
+
+func (fm *feederMetrics) update(block Block, f func(bm *blockMetrics)) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	bm, ok := fm.blocks[block.Info.Instance]
+	if !ok {
+		if fm.bar != nil {
+			// a block of a stopped bar
+			return
+		}
+		bm = &blockMetrics{}
+		fm.blocks[block.Info.Instance] = bm
+	}
+	bm.module = block.ModuleName
+	f(bm)
+}
+
+func (fm *feederMetrics) observeUpdate(block Block, d time.Duration) {
+	fm.update(block, func(bm *blockMetrics) {
+		bm.updates++
+		bm.updateSeconds += d.Seconds()
+		bm.lastUpdate = d.Seconds()
+	})
+}
+
+func (fm *feederMetrics) incErrors(block Block) {
+	fm.update(block, func(bm *blockMetrics) { bm.errors++ })
+}
+
+func (fm *feederMetrics) incPanics(block Block) {
+	fm.update(block, func(bm *blockMetrics) { bm.panics++ })
+}
+
+func (fm *feederMetrics) incClicks(block Block) {
+	fm.update(block, func(bm *blockMetrics) { bm.clicks++ })
+}
+
+// setBar replaces the blocks with the blocks of the bar, the counters of the
+// kept blocks continue.
+func (fm *feederMetrics) setBar(bar *Bar) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	fm.bar = bar
+	blocks := make(map[string]*blockMetrics, len(bar.blocks))
+	for _, block := range bar.blocks {
+		bm, ok := fm.blocks[block.Info.Instance]
+		if !ok || bm.module != block.ModuleName {
+			bm = &blockMetrics{module: block.ModuleName}
+		}
+		blocks[block.Info.Instance] = bm
+	}
+	fm.blocks = blocks
+}
+
+// listen (re)starts the HTTP listener when the address changed.
+func (fm *feederMetrics) listen(config *MetricsConfig) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	if fm.server != nil {
+		if config != nil && config.Listen == fm.server.Addr {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		fm.server.Shutdown(ctx)
+		cancel()
+		fm.server = nil
+	}
+	if config == nil || config.Listen == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", fm.serveHTTP)
+	server := &http.Server{Addr: config.Listen, Handler: mux}
+	fm.server = server
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			xlog.Errorf("Metrics listener error: %s", err)
+		}
+	}()
+}
+
+func (fm *feederMetrics) serveHTTP(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	families := map[string][]string{}
+	fm.mu.Lock()
+	bar := fm.bar
+	fm.mu.Unlock()
+	if bar != nil {
+		bar.mu.Lock()
+		blocks := make([]Block, len(bar.blocks))
+		copy(blocks, bar.blocks)
+		bar.mu.Unlock()
+		for _, block := range blocks {
+			provider, ok := block.module.(MetricsProvider)
+			if !ok {
+				continue
+			}
+			for name, value := range provider.Metrics() {
+				name = "i3barfeeder_" + name
+				families[name] = append(families[name], sample(name, block.Info.Instance, block.ModuleName, value))
+			}
+		}
+	}
+	fm.mu.Lock()
+	for instance, bm := range fm.blocks {
+		add := func(name string, value float64) {
+			name = "i3barfeeder_" + name
+			families[name] = append(families[name], sample(name, instance, bm.module, value))
+		}
+		add("block_updates_total", float64(bm.updates))
+		add("block_update_seconds_total", bm.updateSeconds)
+		add("block_last_update_seconds", bm.lastUpdate)
+		add("block_errors_total", float64(bm.errors))
+		add("block_panics_total", float64(bm.panics))
+		add("block_clicks_total", float64(bm.clicks))
+	}
+	fm.mu.Unlock()
+
+	var names []string
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		typ := "gauge"
+		if strings.HasSuffix(name, "_total") {
+			typ = "counter"
+		}
+		samples := families[name]
+		sort.Strings(samples)
+		fmt.Fprintf(rw, "# TYPE %s %s\n%s\n", name, typ, strings.Join(samples, "\n"))
+	}
+}
+
+func sample(name string, instance string, module string, value float64) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return fmt.Sprintf(`%s{block="%s",module="%s"} %g`, name, r.Replace(instance), r.Replace(module), value)
+}
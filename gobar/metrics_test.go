@@ -0,0 +1,59 @@
+package gobar
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/internal/state"
+)
+
+// failingModule fails every update.
+type failingModule struct {
+	ModuleInterface
+}
+
+func (m *failingModule) InitModule(config json.RawMessage, log xlog.Logger) error {
+	return nil
+}
+
+func (m *failingModule) UpdateInfo(info BlockInfo) BlockInfo {
+	info.FullText = "unavailable"
+	info.Failed = true
+	return info
+}
+
+func TestUpdateErrors(t *testing.T) {
+	AddModule("failingTest", func() ModuleInterface { return &failingModule{} })
+	defer delete(moduleRegistry, "failingTest")
+	blockErrors := func() uint64 {
+		metrics.mu.Lock()
+		defer metrics.mu.Unlock()
+		if bm, ok := metrics.blocks["failing"]; ok {
+			return bm.errors
+		}
+		return 0
+	}
+	before := blockErrors()
+	// the update is not received, the block waits for the bar forever
+	(&Config{
+		Blocks: []Block{{ID: "failing", ModuleName: "failingTest"}},
+		State:  &state.Config{Path: filepath.Join(t.TempDir(), "state.json")},
+	}).NewBar(strings.NewReader(""), io.Discard)
+	deadline := time.Now().Add(time.Second)
+	for blockErrors() != before+1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("errors: %d, expected: %d", blockErrors(), before+1)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	data, _ := json.Marshal(BlockInfo{FullText: "unavailable", Failed: true})
+	if strings.Contains(string(data), "ailed") {
+		t.Errorf("the failure is sent to i3bar: %s", data)
+	}
+}
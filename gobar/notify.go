@@ -0,0 +1,69 @@
+package gobar
+
+import (
+	"sync"
+
+	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/internal/notify"
+)
+
+var (
+	notifyMu     sync.Mutex
+	notifier     *notify.Notifier
+	notifySender notify.Sender
+	// replaces the D-Bus sender when set, see SetNotifySender
+	testSender notify.Sender
+)
+
+// SetNotifySender replaces the D-Bus sender of the notifications, it is used
+// by the next configuration. Nil restores the D-Bus sender.
+func SetNotifySender(sender notify.Sender) {
+	notifyMu.Lock()
+	defer notifyMu.Unlock()
+	testSender = sender
+}
+
+func setupNotifier(config *notify.Config) {
+	notifyMu.Lock()
+	defer notifyMu.Unlock()
+	if closer, ok := notifySender.(interface{ Close() error }); ok {
+		closer.Close()
+	}
+	if config == nil {
+		notifier, notifySender = nil, nil
+		return
+	}
+	notifySender = testSender
+	if notifySender == nil {
+		notifySender = notify.NewDBus(config.Address, "i3barfeeder")
+	}
+	notifier = notify.New(notifySender, *config)
+	notifier.SetClock(Now)
+}
+
+// Notify sends a desktop notification, it does nothing when the
+// notifications are not configured.
+func Notify(n notify.Notification) {
+	notifyMu.Lock()
+	current := notifier
+	notifyMu.Unlock()
+	if current == nil {
+		return
+	}
+	err := current.Notify(n)
+	if err != nil && err != notify.ErrDuplicate {
+		xlog.Warnf("Unable to send notification %q: %s", n.Summary, err)
+	}
+}
+
+// Notifications returns the recently sent notifications.
+func (b *Bar) Notifications() []notify.Notification {
+	notifyMu.Lock()
+	current := notifier
+	notifyMu.Unlock()
+	if current == nil {
+		return nil
+	}
+	return current.History()
+}
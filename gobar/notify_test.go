@@ -0,0 +1,97 @@
+package gobar
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ak-Army/i3barfeeder/internal/notify"
+)
+
+type fixedClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fixedClock) After(time.Duration) <-chan time.Time {
+	return nil
+}
+
+func (c *fixedClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+type recordSender struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (s *recordSender) Send(n notify.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, n.Summary)
+	return nil
+}
+
+func (s *recordSender) summaries() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strings.Join(s.sent, ",")
+}
+
+func TestNotify(t *testing.T) {
+	// NewBar opens the default state file
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	clock := &fixedClock{now: time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)}
+	SetClock(clock)
+	defer SetClock(nil)
+	sender := &recordSender{}
+	SetNotifySender(sender)
+	defer SetNotifySender(nil)
+	bar := (&Config{
+		Notifications: &notify.Config{RateLimit: 2, Dedup: 60},
+	}).NewBar(strings.NewReader(""), io.Discard)
+	defer setupNotifier(nil)
+
+	Notify(notify.Notification{Key: "disk", Summary: "disk"})
+	Notify(notify.Notification{Key: "disk", Summary: "disk"})
+	if got := sender.summaries(); got != "disk" {
+		t.Fatalf("duplicated notification sent: %s", got)
+	}
+	clock.advance(61 * time.Second)
+	Notify(notify.Notification{Key: "disk", Summary: "disk"})
+	Notify(notify.Notification{Summary: "timer", Body: "running\nfor 9h"})
+	Notify(notify.Notification{Summary: "meeting"})
+	if got := sender.summaries(); got != "disk,disk,timer" {
+		t.Fatalf("rate limit: got %s", got)
+	}
+
+	store := &Store{bar: bar}
+	path := filepath.Join(t.TempDir(), "ipc.sock")
+	l, err := store.ListenIPC(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	answer, err := SendAction(path, "notifications")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "2026-03-02T10:00:00Z disk\n" +
+		"2026-03-02T10:01:01Z disk\n" +
+		"2026-03-02T10:01:01Z timer: running for 9h\n"
+	if answer != expected {
+		t.Errorf("notifications:\n%s\nexpected:\n%s", answer, expected)
+	}
+}
@@ -1,7 +1,35 @@
 package gobar
 
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/Ak-Army/xlog"
+)
+
 var moduleRegistry = make(map[string]func() ModuleInterface)
 
 func AddModule(name string, module func() ModuleInterface) {
 	moduleRegistry[name] = module
 }
+
+// Modules returns the names of the registered modules.
+func Modules() []string {
+	var names []string
+	for name := range moduleRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewModule creates and initializes a registered module by name.
+func NewModule(name string, config json.RawMessage, log xlog.Logger) (ModuleInterface, error) {
+	module, ok := moduleRegistry[name]
+	if !ok {
+		return nil, fmt.Errorf("module not found: `%s`", name)
+	}
+	m := module()
+	return m, m.InitModule(config, log)
+}
@@ -0,0 +1,92 @@
+package gobar
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/internal/state"
+)
+
+const stateSaveInterval = time.Minute
+
+var (
+	statesMu sync.Mutex
+	// the store of the last configuration, the bars keep their own
+	states *state.Store
+)
+
+func setupState(config *state.Config) *state.Store {
+	if config == nil {
+		config = &state.Config{}
+	}
+	path := config.Path
+	if path == "" {
+		path = state.DefaultPath()
+	}
+	store, err := state.Open(path)
+	if err != nil {
+		xlog.Warnf("Module states are not kept: %s", err)
+	}
+	statesMu.Lock()
+	defer statesMu.Unlock()
+	states = store
+	return store
+}
+
+func (block *Block) restoreState(log xlog.Logger) {
+	statesMu.Lock()
+	states := states
+	statesMu.Unlock()
+	saver, ok := block.module.(StateSaver)
+	if !ok || states == nil {
+		return
+	}
+	data := states.Get(block.Info.Instance, block.ModuleName)
+	if data == nil {
+		return
+	}
+	if err := saver.RestoreState(data); err != nil {
+		log.Warnf("Unable to restore the state of %s: %s", block.Info.Instance, err)
+	}
+}
+
+// SaveState writes the state of the modules to the state file.
+func (b *Bar) SaveState() {
+	if b.states == nil {
+		return
+	}
+	b.mu.Lock()
+	blocks := make([]Block, len(b.blocks))
+	copy(blocks, b.blocks)
+	b.mu.Unlock()
+	for _, block := range blocks {
+		saver, ok := block.module.(StateSaver)
+		if !ok {
+			continue
+		}
+		data, err := saver.SaveState()
+		if err != nil {
+			b.log.Warnf("Unable to save the state of %s: %s", block.Info.Instance, err)
+			continue
+		}
+		b.states.Set(block.Info.Instance, block.ModuleName, json.RawMessage(data))
+	}
+	if err := b.states.Save(); err != nil {
+		b.log.Warnf("Unable to write the state file: %s", err)
+	}
+}
+
+func (b *Bar) saveStates() {
+	for {
+		select {
+		case <-b.stop:
+			b.log.Debug("Stop saveStates")
+			return
+		case <-clock.After(stateSaveInterval):
+			b.SaveState()
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package gobar
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Ak-Army/i3barfeeder/internal/state"
+)
+
+func TestSaveStateDuringReload(t *testing.T) {
+	dir := t.TempDir()
+	newBar := func(name string) *Bar {
+		return (&Config{
+			State: &state.Config{Path: filepath.Join(dir, name)},
+		}).NewBar(strings.NewReader(""), io.Discard)
+	}
+	old := newBar("old.json")
+	saved := make(chan struct{})
+	go func() {
+		defer close(saved)
+		for i := 0; i < 100; i++ {
+			old.SaveState()
+		}
+	}()
+	reloaded := newBar("new.json")
+	<-saved
+	if old.states == reloaded.states {
+		t.Error("the reloaded bar uses the state store of the old one")
+	}
+}
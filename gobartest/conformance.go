@@ -0,0 +1,113 @@
+package gobartest
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/gobar"
+)
+
+// ConformanceAll runs Conformance for every registered module, the configs
+// are used for the modules which can not start with an empty config. Every
+// module has to start, a failing InitModule fails the test.
+func ConformanceAll(t *testing.T, configs map[string]json.RawMessage) {
+	for _, name := range gobar.Modules() {
+		t.Run(name, func(t *testing.T) {
+			Conformance(t, name, configs[name])
+		})
+	}
+}
+
+// Conformance checks the rules every gobar.ModuleInterface has to follow:
+// it must not panic, must keep the name and the instance of the block and
+// must restore its own saved state.
+func Conformance(t *testing.T, name string, config json.RawMessage) {
+	var module gobar.ModuleInterface
+	err := noPanic(func() error {
+		var err error
+		module, err = gobar.NewModule(name, config, xlog.GetLogger())
+		return err
+	})
+	if err != nil {
+		t.Fatalf("InitModule: %s", err)
+	}
+	info := gobar.BlockInfo{Name: name, Instance: "conformance"}
+	t.Run("UpdateInfo", func(t *testing.T) {
+		err := noPanic(func() error {
+			return checkInfo(module.UpdateInfo(info), info)
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("HandleClick", func(t *testing.T) {
+		for button := 1; button <= 5; button++ {
+			err := noPanic(func() error {
+				newInfo, _ := module.HandleClick(gobar.ClickMessage{
+					Name:     info.Name,
+					Instance: info.Instance,
+					Button:   button,
+				}, info)
+				if newInfo == nil {
+					return nil
+				}
+				return checkInfo(*newInfo, info)
+			})
+			if err != nil {
+				t.Errorf("button %d: %s", button, err)
+			}
+		}
+	})
+	if saver, ok := module.(gobar.StateSaver); ok {
+		t.Run("State", func(t *testing.T) {
+			err := noPanic(func() error {
+				state, err := saver.SaveState()
+				if err != nil {
+					return err
+				}
+				return saver.RestoreState(state)
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	if closer, ok := module.(gobar.Closer); ok {
+		t.Run("Close", func(t *testing.T) {
+			// the bar closes a module once, a second close must not panic
+			for i := 0; i < 2; i++ {
+				if err := noPanic(closer.Close); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+type panicError struct {
+	value interface{}
+}
+
+func (p panicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.value)
+}
+
+func noPanic(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = panicError{value: r}
+		}
+	}()
+	return f()
+}
+
+func checkInfo(got gobar.BlockInfo, expected gobar.BlockInfo) error {
+	if got.Name != expected.Name || got.Instance != expected.Instance {
+		return fmt.Errorf("block identity changed: %s/%s -> %s/%s",
+			expected.Name, expected.Instance, got.Name, got.Instance)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package gobartest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("gobartest.update", false, "update the golden files")
+
+// AssertGolden compares the JSON form of got with the golden file, the file
+// is rewritten when the tests run with -gobartest.update.
+func AssertGolden(t testing.TB, path string, got interface{}) {
+	t.Helper()
+	actual, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatalf("unable to marshal: %s", err)
+	}
+	actual = append(actual, '\n')
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unable to read golden file, run with -gobartest.update: %s", err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("%s mismatch\nexpected:\n%s\ngot:\n%s", path, expected, actual)
+	}
+}
@@ -0,0 +1,206 @@
+package gobartest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Ak-Army/i3barfeeder/gobar"
+	"github.com/Ak-Army/i3barfeeder/internal/state"
+)
+
+type Header struct {
+	Version     int  `json:"version"`
+	ClickEvents bool `json:"click_events"`
+}
+
+// I3Bar plays the i3bar side of the protocol: it parses the header and the
+// status lines written by the bar and sends click events to it.
+type I3Bar struct {
+	Bar      *gobar.Bar
+	mu       sync.Mutex
+	clickIn  *io.PipeWriter
+	statusIn *io.PipeReader
+	header   *Header
+	statuses [][]gobar.BlockInfo
+	clicks   int
+	changed  chan struct{}
+	err      error
+}
+
+// Start creates the bar from the config and waits for the protocol header.
+// The module states go to a temporary file unless the config sets one.
+func Start(config *gobar.Config) (*I3Bar, error) {
+	if config.State == nil {
+		config.State = &state.Config{
+			Path: filepath.Join(os.TempDir(), fmt.Sprintf("gobartest-%d.json", os.Getpid())),
+		}
+	}
+	clickOut, clickIn := io.Pipe()
+	statusIn, statusOut := io.Pipe()
+	b := &I3Bar{
+		Bar:      config.NewBar(clickOut, statusOut),
+		clickIn:  clickIn,
+		statusIn: statusIn,
+		changed:  make(chan struct{}),
+	}
+	go b.read()
+	go b.Bar.Start()
+	err := b.wait(5*time.Second, func() bool {
+		return b.header != nil
+	})
+	if err != nil {
+		b.Stop()
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *I3Bar) read() {
+	dec := json.NewDecoder(b.statusIn)
+	var header Header
+	err := dec.Decode(&header)
+	if err == nil {
+		b.setState(func() { b.header = &header })
+		_, err = dec.Token()
+	}
+	for err == nil && dec.More() {
+		var status []gobar.BlockInfo
+		if err = dec.Decode(&status); err == nil {
+			b.setState(func() { b.statuses = append(b.statuses, status) })
+		}
+	}
+	if err == nil {
+		err = io.EOF
+	}
+	b.setState(func() { b.err = err })
+}
+
+func (b *I3Bar) setState(f func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	f()
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+func (b *I3Bar) wait(timeout time.Duration, cond func() bool) error {
+	deadline := time.After(timeout)
+	for {
+		b.mu.Lock()
+		ok, err, changed := cond(), b.err, b.changed
+		b.mu.Unlock()
+		if ok {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return errors.New("timeout")
+		}
+	}
+}
+
+func (b *I3Bar) Header() Header {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.header == nil {
+		return Header{}
+	}
+	return *b.header
+}
+
+// Statuses returns every status line received so far.
+func (b *I3Bar) Statuses() [][]gobar.BlockInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([][]gobar.BlockInfo{}, b.statuses...)
+}
+
+// Last returns the last received status line.
+func (b *I3Bar) Last() []gobar.BlockInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.statuses) == 0 {
+		return nil
+	}
+	return b.statuses[len(b.statuses)-1]
+}
+
+// WaitFor waits until a status line received after the call matches.
+func (b *I3Bar) WaitFor(timeout time.Duration, match func(status []gobar.BlockInfo) bool) ([]gobar.BlockInfo, error) {
+	b.mu.Lock()
+	from := len(b.statuses)
+	b.mu.Unlock()
+	var found []gobar.BlockInfo
+	err := b.wait(timeout, func() bool {
+		for _, status := range b.statuses[from:] {
+			if match(status) {
+				found = status
+				return true
+			}
+		}
+		from = len(b.statuses)
+		return false
+	})
+	return found, err
+}
+
+// Click sends a click event the same way as i3bar does.
+func (b *I3Bar) Click(cm gobar.ClickMessage) error {
+	line, err := json.Marshal(cm)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	prefix := ","
+	if b.clicks == 0 {
+		prefix = "[\n"
+	}
+	b.clicks++
+	_, err = fmt.Fprintf(b.clickIn, "%s%s\n", prefix, line)
+	return err
+}
+
+// ClickBlock clicks the block with the given instance.
+func (b *I3Bar) ClickBlock(instance string, button int) error {
+	info, ok := Find(b.Last(), instance)
+	if !ok {
+		return fmt.Errorf("block not found: %s", instance)
+	}
+	return b.Click(gobar.ClickMessage{
+		Name:     info.Name,
+		Instance: info.Instance,
+		Button:   button,
+	})
+}
+
+func (b *I3Bar) Stop() {
+	b.mu.Lock()
+	started := b.header != nil
+	b.mu.Unlock()
+	if started {
+		b.Bar.Stop()
+	}
+	b.clickIn.Close()
+	b.statusIn.Close()
+}
+
+// Find returns the block with the given instance from a status line.
+func Find(status []gobar.BlockInfo, instance string) (gobar.BlockInfo, bool) {
+	for _, info := range status {
+		if info.Instance == instance {
+			return info, true
+		}
+	}
+	return gobar.BlockInfo{}, false
+}
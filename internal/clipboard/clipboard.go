@@ -0,0 +1,125 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	BackendXClip  = "xclip"
+	BackendXSel   = "xsel"
+	BackendWLCopy = "wl-copy"
+	BackendOSC52  = "osc52"
+)
+
+var ErrNoBackend = errors.New("no clipboard backend found, install wl-copy, xclip or xsel")
+
+var commands = map[string][]string{
+	BackendXClip:  {"xclip", "-selection", "clipboard"},
+	BackendXSel:   {"xsel", "--clipboard", "--input"},
+	BackendWLCopy: {"wl-copy"},
+}
+
+type Config struct {
+	// One of xclip, xsel, wl-copy or osc52, detected when empty
+	Backend string `config:"backend" json:"backend"`
+	// Command reading the text on its stdin, overrides the backend
+	Command []string `config:"command" json:"command"`
+	// Terminal receiving the OSC 52 sequence
+	TTY string `config:"tty" json:"tty"`
+}
+
+type Clipboard struct {
+	config   Config
+	getenv   func(string) string
+	lookPath func(string) (string, error)
+}
+
+func New(config Config) *Clipboard {
+	if config.TTY == "" {
+		config.TTY = "/dev/tty"
+	}
+	return &Clipboard{
+		config:   config,
+		getenv:   os.Getenv,
+		lookPath: exec.LookPath,
+	}
+}
+
+// Backend returns the configured or the detected backend.
+func (c *Clipboard) Backend() (string, error) {
+	if len(c.config.Command) > 0 {
+		return c.config.Command[0], nil
+	}
+	if c.config.Backend != "" {
+		if _, ok := commands[c.config.Backend]; !ok && c.config.Backend != BackendOSC52 {
+			return "", fmt.Errorf("unknown clipboard backend: `%s`", c.config.Backend)
+		}
+		return c.config.Backend, nil
+	}
+	var try []string
+	if c.getenv("WAYLAND_DISPLAY") != "" {
+		try = append(try, BackendWLCopy)
+	}
+	if c.getenv("DISPLAY") != "" {
+		try = append(try, BackendXClip, BackendXSel)
+	}
+	for _, backend := range try {
+		if _, err := c.lookPath(commands[backend][0]); err == nil {
+			return backend, nil
+		}
+	}
+	if _, err := os.Stat(c.config.TTY); err == nil {
+		return BackendOSC52, nil
+	}
+	return "", ErrNoBackend
+}
+
+func (c *Clipboard) Copy(text string) error {
+	backend, err := c.Backend()
+	if err != nil {
+		return err
+	}
+	if backend == BackendOSC52 {
+		return c.osc52(text)
+	}
+	argv := c.config.Command
+	if len(argv) == 0 {
+		argv = commands[backend]
+	}
+	// xclip and wl-copy stay in the background to serve the selection, they
+	// keep the inherited stdout and stderr open: a pipe would block until
+	// the selection changes, stderr goes to a file.
+	stderr, err := os.CreateTemp("", "i3barfeeder-clipboard")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(stderr.Name())
+	defer stderr.Close()
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if out, readErr := os.ReadFile(stderr.Name()); readErr == nil {
+			if msg := strings.TrimSpace(string(out)); msg != "" {
+				return fmt.Errorf("%s: %s", backend, msg)
+			}
+		}
+		return fmt.Errorf("%s: %s", backend, err)
+	}
+	return nil
+}
+
+func (c *Clipboard) osc52(text string) error {
+	tty, err := os.OpenFile(c.config.TTY, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("osc52: %s", err)
+	}
+	defer tty.Close()
+	_, err = fmt.Fprintf(tty, "\x1b]52;c;%s\x07", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
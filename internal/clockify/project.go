@@ -1,6 +1,7 @@
 package clockify
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -274,9 +275,9 @@ func (t Tags) FindByName(name string) *Tag {
 	return nil
 }
 
-func (c *Client) GetWorkspaceProjects(wid string) (Projects, error) {
+func (c *Client) GetWorkspaceProjects(ctx context.Context, wid string) (Projects, error) {
 	var projects Projects
-	res, err := c.request("GET", fmt.Sprintf("/workspaces/%s/projects", wid), nil)
+	res, err := c.request(ctx, "GET", fmt.Sprintf("/workspaces/%s/projects", wid), nil)
 	if err != nil {
 		return projects, err
 	}
@@ -284,12 +285,22 @@ func (c *Client) GetWorkspaceProjects(wid string) (Projects, error) {
 	return projects, err
 }
 
-func (c *Client) GetWorkspaceTags(wid string) (Tags, error) {
+func (c *Client) GetWorkspaceTags(ctx context.Context, wid string) (Tags, error) {
 	var tags Tags
-	res, err := c.request("GET", fmt.Sprintf("/workspaces/%s/tags", wid), nil)
+	res, err := c.request(ctx, "GET", fmt.Sprintf("/workspaces/%s/tags", wid), nil)
 	if err != nil {
 		return tags, err
 	}
 	err = json.Unmarshal(res, &tags)
 	return tags, err
 }
+
+func (c *Client) GetProjectTasks(ctx context.Context, wid string, pid string) (Tasks, error) {
+	var tasks Tasks
+	res, err := c.request(ctx, "GET", fmt.Sprintf("/workspaces/%s/projects/%s/tasks", wid, pid), nil)
+	if err != nil {
+		return tasks, err
+	}
+	err = json.Unmarshal(res, &tasks)
+	return tasks, err
+}
@@ -1,6 +1,7 @@
 package clockify
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -18,6 +19,8 @@ type TimeEntry struct {
 	ID           string       `json:"id"`
 	IsLocked     bool         `json:"isLocked"`
 	ProjectID    string       `json:"projectId"`
+	TaskID       string       `json:"taskId"`
+	TagIDs       []string     `json:"tagIds"`
 	TimeInterval TimeInterval `json:"timeInterval"`
 	UserID       string       `json:"userId"`
 	WorkspaceID  string       `json:"workspaceId"`
@@ -33,8 +36,8 @@ type currentResponse struct {
 	Data TimeEntry `json:"data"`
 }
 
-func (c *Client) GetCurrentTimeEntry(wid string, user string) (TimeEntry, error) {
-	res, err := c.request("GET", fmt.Sprintf("/workspaces/%s/user/%s/time-entries?in-progress=true", wid, user), nil)
+func (c *Client) GetCurrentTimeEntry(ctx context.Context, wid string, user string) (TimeEntry, error) {
+	res, err := c.request(ctx, "GET", fmt.Sprintf("/workspaces/%s/user/%s/time-entries?in-progress=true", wid, user), nil)
 	if err != nil {
 		return TimeEntry{}, err
 	}
@@ -49,19 +52,21 @@ func (c *Client) GetCurrentTimeEntry(wid string, user string) (TimeEntry, error)
 	return response[0], nil
 }
 
-func (c *Client) StartTimeEntry(timeEntry TimeEntry) (TimeEntry, error) {
+func (c *Client) StartTimeEntry(ctx context.Context, timeEntry TimeEntry) (TimeEntry, error) {
 	update := &updateTimeEntryRequest{
 		Billable:    timeEntry.Billable,
 		Description: timeEntry.Description,
 		ProjectID:   timeEntry.ProjectID,
+		TaskID:      timeEntry.TaskID,
+		TagIDs:      timeEntry.TagIDs,
 		Start:       &DateTime{Time: timeEntry.TimeInterval.Start},
 	}
 	if timeEntry.TimeInterval.End != nil {
 		update.End = &DateTime{Time: *timeEntry.TimeInterval.End}
 	}
-	res, err := c.request("POST", fmt.Sprintf("/workspaces/%s/time-entries", timeEntry.WorkspaceID), update)
+	res, err := c.request(ctx, "POST", fmt.Sprintf("/workspaces/%s/time-entries", timeEntry.WorkspaceID), update)
 	if err != nil {
-		xlog.Errorf("Unable to start time entry: %#v", string(res), err)
+		xlog.Errorf("Unable to start time entry: %s", err)
 		return TimeEntry{}, err
 	}
 	var response = TimeEntry{}
@@ -72,11 +77,17 @@ func (c *Client) StartTimeEntry(timeEntry TimeEntry) (TimeEntry, error) {
 	return response, nil
 }
 
-func (c *Client) StopTimeEntry(timeEntry TimeEntry) (TimeEntry, error) {
-	res, err := c.request("PATCH",
+// StopTimeEntry stops the running entry at its end, or now when it is not
+// set.
+func (c *Client) StopTimeEntry(ctx context.Context, timeEntry TimeEntry) (TimeEntry, error) {
+	end := time.Now()
+	if timeEntry.TimeInterval.End != nil {
+		end = *timeEntry.TimeInterval.End
+	}
+	res, err := c.request(ctx, "PATCH",
 		fmt.Sprintf("/workspaces/%s/user/%s/time-entries", timeEntry.WorkspaceID, timeEntry.UserID),
 		&updateTimeEntryRequest{
-			End: &DateTime{Time: time.Now()},
+			End: &DateTime{Time: end},
 		})
 
 	if err != nil {
@@ -90,26 +101,38 @@ func (c *Client) StopTimeEntry(timeEntry TimeEntry) (TimeEntry, error) {
 	return response, nil
 }
 
-func (c *Client) GetTimeEntries(wid string, user string, fromDate time.Time, toDate time.Time) ([]TimeEntry, error) {
+// timeEntriesPageSize is the page size of GetTimeEntries, the API returns 50
+// entries by default.
+const timeEntriesPageSize = 200
+
+func (c *Client) GetTimeEntries(ctx context.Context, wid string, user string, fromDate time.Time, toDate time.Time) ([]TimeEntry, error) {
 	var response []TimeEntry
-	endpoint := fmt.Sprintf("/workspaces/%s/user/%s/time-entries", wid, user)
+	query := url.Values{}
 	if !fromDate.IsZero() {
-		endpoint += "?start=" + url.QueryEscape(fromDate.Format(dateFormatISO8601))
+		query.Set("start", fromDate.UTC().Format(dateFormatISO8601))
 		if !toDate.IsZero() {
-			endpoint += "&end=" + url.QueryEscape(toDate.Format(dateFormatISO8601))
+			query.Set("end", toDate.UTC().Format(dateFormatISO8601))
 		} else {
-			endpoint += "&end=" + url.QueryEscape(fromDate.Add(24*time.Hour).Format(dateFormatISO8601))
+			query.Set("end", fromDate.Add(24*time.Hour).UTC().Format(dateFormatISO8601))
 		}
 	}
-	res, err := c.request("GET", endpoint, nil)
-	if err != nil {
-		return response, err
-	}
-	err = json.Unmarshal(res, &response)
-	if err != nil {
-		return response, err
+	query.Set("page-size", strconv.Itoa(timeEntriesPageSize))
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		endpoint := fmt.Sprintf("/workspaces/%s/user/%s/time-entries?%s", wid, user, query.Encode())
+		res, err := c.request(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return response, err
+		}
+		var entries []TimeEntry
+		if err := json.Unmarshal(res, &entries); err != nil {
+			return response, err
+		}
+		response = append(response, entries...)
+		if len(entries) < timeEntriesPageSize {
+			return response, nil
+		}
 	}
-	return response, nil
 }
 
 // UpdateTimeEntryRequest to update a time entry
@@ -147,21 +170,23 @@ type CustomFieldValue struct {
 	Value         string `json:"value"`
 }
 
-func (c *Client) UpdateTimeEntry(timeEntry TimeEntry) (TimeEntry, error) {
+func (c *Client) UpdateTimeEntry(ctx context.Context, timeEntry TimeEntry) (TimeEntry, error) {
 	update := &updateTimeEntryRequest{
 		Billable:    timeEntry.Billable,
 		Description: timeEntry.Description,
 		ProjectID:   timeEntry.ProjectID,
+		TaskID:      timeEntry.TaskID,
+		TagIDs:      timeEntry.TagIDs,
 		Start:       &DateTime{Time: timeEntry.TimeInterval.Start},
 	}
 	if timeEntry.TimeInterval.End != nil {
 		update.End = &DateTime{Time: *timeEntry.TimeInterval.End}
 	}
-	res, err := c.request("PUT",
+	res, err := c.request(ctx, "PUT",
 		fmt.Sprintf("/workspaces/%s/time-entries/%s", timeEntry.WorkspaceID, timeEntry.ID), update)
 
 	if err != nil {
-		xlog.Errorf("Unable to update time entry: %#v", res, err)
+		xlog.Errorf("Unable to update time entry: %s", err)
 		return TimeEntry{}, err
 	}
 	var response = TimeEntry{}
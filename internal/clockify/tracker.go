@@ -0,0 +1,246 @@
+package clockify
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/internal/tracker"
+)
+
+// Tracker is the Clockify backend of the time tracking block, it works in
+// the given workspace or in the default workspace of the user.
+type Tracker struct {
+	mu        sync.Mutex
+	client    Client
+	workspace string
+	user      *User
+	tags      Tags
+}
+
+func NewTracker(apiToken string, workspace string, log xlog.Logger) *Tracker {
+	return &Tracker{
+		client:    NewClient(apiToken, log),
+		workspace: workspace,
+	}
+}
+
+func (t *Tracker) Name() string {
+	return "clockify"
+}
+
+// login fetches the user once, its ID is needed by most of the endpoints.
+func (t *Tracker) login(ctx context.Context) (*User, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.user != nil {
+		return t.user, nil
+	}
+	user, err := t.client.User(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("no user found")
+	}
+	if t.workspace == "" {
+		t.workspace = user.DefaultWorkspace
+	}
+	t.user = user
+	return t.user, nil
+}
+
+func (t *Tracker) Current(ctx context.Context) (tracker.Entry, error) {
+	user, err := t.login(ctx)
+	if err != nil {
+		return tracker.Entry{}, err
+	}
+	entry, err := t.client.GetCurrentTimeEntry(ctx, t.workspace, user.ID)
+	if err != nil {
+		return tracker.Entry{}, err
+	}
+	return t.fromTimeEntry(ctx, entry), nil
+}
+
+func (t *Tracker) Start(ctx context.Context, entry tracker.Entry) (tracker.Entry, error) {
+	timeEntry, err := t.toTimeEntry(ctx, entry)
+	if err != nil {
+		return tracker.Entry{}, err
+	}
+	started, err := t.client.StartTimeEntry(ctx, timeEntry)
+	if err != nil {
+		return tracker.Entry{}, err
+	}
+	return t.fromTimeEntry(ctx, started), nil
+}
+
+func (t *Tracker) Stop(ctx context.Context, entry tracker.Entry) (tracker.Entry, error) {
+	timeEntry, err := t.toTimeEntry(ctx, entry)
+	if err != nil {
+		return tracker.Entry{}, err
+	}
+	stopped, err := t.client.StopTimeEntry(ctx, timeEntry)
+	if err != nil {
+		return tracker.Entry{}, err
+	}
+	return t.fromTimeEntry(ctx, stopped), nil
+}
+
+func (t *Tracker) Update(ctx context.Context, entry tracker.Entry) (tracker.Entry, error) {
+	timeEntry, err := t.toTimeEntry(ctx, entry)
+	if err != nil {
+		return tracker.Entry{}, err
+	}
+	updated, err := t.client.UpdateTimeEntry(ctx, timeEntry)
+	if err != nil {
+		return tracker.Entry{}, err
+	}
+	return t.fromTimeEntry(ctx, updated), nil
+}
+
+func (t *Tracker) Entries(ctx context.Context, from time.Time, to time.Time) ([]tracker.Entry, error) {
+	user, err := t.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timeEntries, err := t.client.GetTimeEntries(ctx, t.workspace, user.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]tracker.Entry, 0, len(timeEntries))
+	for _, timeEntry := range timeEntries {
+		entries = append(entries, t.fromTimeEntry(ctx, timeEntry))
+	}
+	return entries, nil
+}
+
+func (t *Tracker) Projects(ctx context.Context) (tracker.Projects, error) {
+	if _, err := t.login(ctx); err != nil {
+		return nil, err
+	}
+	projects, err := t.client.GetWorkspaceProjects(ctx, t.workspace)
+	if err != nil {
+		return nil, err
+	}
+	list := make(tracker.Projects, 0, len(projects))
+	for _, p := range projects {
+		list = append(list, &tracker.Project{
+			ID:     tracker.ID(p.ID),
+			Name:   p.Name,
+			Active: !p.Archived,
+		})
+	}
+	return list, nil
+}
+
+func (t *Tracker) Tasks(ctx context.Context, project *tracker.Project) (tracker.Tasks, error) {
+	if _, err := t.login(ctx); err != nil {
+		return nil, err
+	}
+	tasks, err := t.client.GetProjectTasks(ctx, t.workspace, string(project.ID))
+	if err != nil {
+		return nil, err
+	}
+	list := make(tracker.Tasks, 0, len(tasks))
+	for _, task := range tasks {
+		list = append(list, &tracker.Task{
+			ID:     tracker.ID(task.ID),
+			Name:   task.Name,
+			Active: task.Status == TaskStatusActive,
+		})
+	}
+	return list, nil
+}
+
+func (t *Tracker) Tags(ctx context.Context) ([]string, error) {
+	tags, err := t.loadTags(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names, nil
+}
+
+// loadTags returns the tags of the workspace, the cached ones unless reload
+// is set. The entries refer to the tags by their IDs.
+func (t *Tracker) loadTags(ctx context.Context, reload bool) (Tags, error) {
+	if _, err := t.login(ctx); err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	tags := t.tags
+	t.mu.Unlock()
+	if tags != nil && !reload {
+		return tags, nil
+	}
+	tags, err := t.client.GetWorkspaceTags(ctx, t.workspace)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	t.tags = tags
+	t.mu.Unlock()
+	return tags, nil
+}
+
+func (t *Tracker) toTimeEntry(ctx context.Context, entry tracker.Entry) (TimeEntry, error) {
+	user, err := t.login(ctx)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+	timeEntry := TimeEntry{
+		ID:          string(entry.ID),
+		Description: entry.Description,
+		ProjectID:   string(entry.ProjectID),
+		TaskID:      string(entry.TaskID),
+		UserID:      user.ID,
+		WorkspaceID: string(entry.Workspace),
+		TimeInterval: TimeInterval{
+			Start: entry.Start,
+			End:   entry.Stop,
+		},
+	}
+	if timeEntry.WorkspaceID == "" {
+		timeEntry.WorkspaceID = t.workspace
+	}
+	if len(entry.Tags) > 0 {
+		tags, err := t.loadTags(ctx, false)
+		if err != nil {
+			return TimeEntry{}, err
+		}
+		for _, name := range entry.Tags {
+			if tag := tags.FindByName(name); tag != nil {
+				timeEntry.TagIDs = append(timeEntry.TagIDs, tag.ID)
+			}
+		}
+	}
+	return timeEntry, nil
+}
+
+func (t *Tracker) fromTimeEntry(ctx context.Context, timeEntry TimeEntry) tracker.Entry {
+	entry := tracker.Entry{
+		ID:          tracker.ID(timeEntry.ID),
+		Workspace:   tracker.ID(timeEntry.WorkspaceID),
+		Description: timeEntry.Description,
+		ProjectID:   tracker.ID(timeEntry.ProjectID),
+		TaskID:      tracker.ID(timeEntry.TaskID),
+		Start:       timeEntry.TimeInterval.Start,
+		Stop:        timeEntry.TimeInterval.End,
+	}
+	if len(timeEntry.TagIDs) > 0 {
+		if tags, err := t.loadTags(ctx, false); err == nil {
+			for _, id := range timeEntry.TagIDs {
+				if tag := tags.FindById(id); tag != nil {
+					entry.Tags = append(entry.Tags, tag.Name)
+				}
+			}
+		}
+	}
+	return entry
+}
@@ -1,6 +1,7 @@
 package clockify
 
 import (
+	"context"
 	"encoding/json"
 )
 
@@ -27,9 +28,9 @@ type Membership struct {
 	UserID     string           `json:"userId"`
 }
 
-func (c *Client) User() (*User, error) {
+func (c *Client) User(ctx context.Context) (*User, error) {
 	var response *User
-	res, err := c.request("GET", "/user", nil)
+	res, err := c.request(ctx, "GET", "/user", nil)
 	if err != nil {
 		return nil, err
 	}
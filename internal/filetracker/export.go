@@ -0,0 +1,67 @@
+package filetracker
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	FormatToggl    = "toggl"
+	FormatClockify = "clockify"
+)
+
+// Export writes the stopped entries as a CSV file the Toggl or the Clockify
+// import understands, email is the user of the entries.
+func (t *Tracker) Export(w io.Writer, format string, email string) error {
+	var header []string
+	var row func(start time.Time, stop time.Time, project, task, description, tags string) []string
+	switch format {
+	case FormatToggl:
+		header = []string{"Email", "Project", "Task", "Description", "Start date", "Start time", "Duration", "Tags"}
+		row = func(start time.Time, stop time.Time, project, task, description, tags string) []string {
+			return []string{email, project, task, description,
+				start.Format("2006-01-02"), start.Format("15:04:05"), clockDuration(stop.Sub(start)), tags}
+		}
+	case FormatClockify:
+		header = []string{"Project", "Description", "Task", "Email", "Tags",
+			"Start Date", "Start Time", "End Date", "End Time", "Duration (h)"}
+		row = func(start time.Time, stop time.Time, project, task, description, tags string) []string {
+			return []string{project, description, task, email, tags,
+				start.Format("2006-01-02"), start.Format("15:04:05"),
+				stop.Format("2006-01-02"), stop.Format("15:04:05"), clockDuration(stop.Sub(start))}
+		}
+	default:
+		return fmt.Errorf("unknown export format: `%s`", format)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.load(); err != nil {
+		return err
+	}
+	out := csv.NewWriter(w)
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	for _, entry := range t.entries {
+		if entry.Stop == nil {
+			continue
+		}
+		start := entry.Start.Local()
+		stop := entry.Stop.Local()
+		err := out.Write(row(start, stop, string(entry.ProjectID), string(entry.TaskID),
+			entry.Description, strings.Join(entry.Tags, ", ")))
+		if err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func clockDuration(d time.Duration) string {
+	sec := int64(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", sec/3600, sec/60%60, sec%60)
+}
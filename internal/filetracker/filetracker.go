@@ -0,0 +1,315 @@
+package filetracker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Ak-Army/i3barfeeder/internal/tracker"
+)
+
+// Tracker keeps the time entries in a local append-only JSON lines file, a
+// line is written every time an entry changes and the last one of an ID
+// wins. It needs no web service, the projects and tasks are named in the
+// configuration and the IDs of them are their names.
+type Tracker struct {
+	mu       sync.Mutex
+	path     string
+	projects map[string][]string
+	entries  []tracker.Entry
+	index    map[tracker.ID]int
+	lastID   int64
+	size     int64
+	modTime  time.Time
+}
+
+// New opens the file at path, projects are the task names by project name.
+func New(path string, projects map[string][]string) (*Tracker, error) {
+	t := &Tracker{
+		path:     path,
+		projects: projects,
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *Tracker) Name() string {
+	return "local"
+}
+
+// load reads the file again when it was changed, eg: by an other bar.
+func (t *Tracker) load() error {
+	stat, err := os.Stat(t.path)
+	if os.IsNotExist(err) {
+		t.entries = nil
+		t.index = map[tracker.ID]int{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if t.index != nil && stat.Size() == t.size && stat.ModTime().Equal(t.modTime) {
+		return nil
+	}
+	f, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	t.entries = nil
+	t.index = map[tracker.ID]int{}
+	t.lastID = 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry tracker.Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("%s:%d: %s", t.path, line, err)
+		}
+		t.set(entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	t.size = stat.Size()
+	t.modTime = stat.ModTime()
+	return nil
+}
+
+func (t *Tracker) set(entry tracker.Entry) {
+	if i, ok := t.index[entry.ID]; ok {
+		t.entries[i] = entry
+	} else {
+		t.index[entry.ID] = len(t.entries)
+		t.entries = append(t.entries, entry)
+	}
+	if id := entry.ID.Int(); id > t.lastID {
+		t.lastID = id
+	}
+}
+
+// write appends the entry to the file.
+func (t *Tracker) write(entry tracker.Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(t.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	t.set(entry)
+	if stat, err := os.Stat(t.path); err == nil {
+		t.size = stat.Size()
+		t.modTime = stat.ModTime()
+	}
+	return nil
+}
+
+func (t *Tracker) running() (tracker.Entry, bool) {
+	for i := len(t.entries) - 1; i >= 0; i-- {
+		if t.entries[i].Running() {
+			return t.entries[i], true
+		}
+	}
+	return tracker.Entry{}, false
+}
+
+func (t *Tracker) Current(ctx context.Context) (tracker.Entry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.load(); err != nil {
+		return tracker.Entry{}, err
+	}
+	entry, _ := t.running()
+	return entry, nil
+}
+
+// Start stops the running entry when the new one starts, like Toggl does.
+func (t *Tracker) Start(ctx context.Context, entry tracker.Entry) (tracker.Entry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.load(); err != nil {
+		return tracker.Entry{}, err
+	}
+	if entry.Start.IsZero() {
+		entry.Start = time.Now()
+	}
+	if running, ok := t.running(); ok {
+		stop := entry.Start
+		running.Stop = &stop
+		if err := t.write(running); err != nil {
+			return tracker.Entry{}, err
+		}
+	}
+	entry.ID = tracker.IntID(t.lastID + 1)
+	entry.Stop = nil
+	return entry, t.write(entry)
+}
+
+func (t *Tracker) Stop(ctx context.Context, entry tracker.Entry) (tracker.Entry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stored, err := t.find(entry.ID)
+	if err != nil {
+		return tracker.Entry{}, err
+	}
+	stop := time.Now()
+	if entry.Stop != nil {
+		stop = *entry.Stop
+	}
+	stored.Stop = &stop
+	return stored, t.write(stored)
+}
+
+func (t *Tracker) Update(ctx context.Context, entry tracker.Entry) (tracker.Entry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stored, err := t.find(entry.ID)
+	if err != nil {
+		return tracker.Entry{}, err
+	}
+	if entry.Start.IsZero() {
+		entry.Start = stored.Start
+	}
+	if entry.Stop == nil {
+		entry.Stop = stored.Stop
+	}
+	return entry, t.write(entry)
+}
+
+func (t *Tracker) find(id tracker.ID) (tracker.Entry, error) {
+	if err := t.load(); err != nil {
+		return tracker.Entry{}, err
+	}
+	i, ok := t.index[id]
+	if !ok {
+		return tracker.Entry{}, fmt.Errorf("time entry not found: %s", id)
+	}
+	return t.entries[i], nil
+}
+
+// Entries returns the entries started between from and to, or in the day
+// after from when to is zero.
+func (t *Tracker) Entries(ctx context.Context, from time.Time, to time.Time) ([]tracker.Entry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	if to.IsZero() && !from.IsZero() {
+		to = from.Add(24 * time.Hour)
+	}
+	var entries []tracker.Entry
+	for _, entry := range t.entries {
+		if (!from.IsZero() && entry.Start.Before(from)) || (!to.IsZero() && !entry.Start.Before(to)) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Start.Before(entries[j].Start)
+	})
+	return entries, nil
+}
+
+// Projects returns the configured projects and the ones of the entries.
+func (t *Tracker) Projects(ctx context.Context) (tracker.Projects, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	var projects tracker.Projects
+	add := func(name string) {
+		if name != "" && projects.FindByName(name) == nil {
+			projects = append(projects, &tracker.Project{
+				ID:     tracker.ID(name),
+				Name:   name,
+				Active: true,
+			})
+		}
+	}
+	names := make([]string, 0, len(t.projects))
+	for name := range t.projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add(name)
+	}
+	for _, entry := range t.entries {
+		add(string(entry.ProjectID))
+	}
+	return projects, nil
+}
+
+func (t *Tracker) Tasks(ctx context.Context, project *tracker.Project) (tracker.Tasks, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	tasks := tracker.Tasks{}
+	add := func(name string) {
+		if name != "" && tasks.FindByName(name) == nil {
+			tasks = append(tasks, &tracker.Task{
+				ID:     tracker.ID(name),
+				Name:   name,
+				Active: true,
+			})
+		}
+	}
+	for _, name := range t.projects[project.Name] {
+		add(name)
+	}
+	for _, entry := range t.entries {
+		if entry.ProjectID == project.ID {
+			add(string(entry.TaskID))
+		}
+	}
+	return tasks, nil
+}
+
+func (t *Tracker) Tags(ctx context.Context) ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var tags []string
+	for _, entry := range t.entries {
+		for _, tag := range entry.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
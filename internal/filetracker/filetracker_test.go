@@ -0,0 +1,195 @@
+package filetracker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ak-Army/i3barfeeder/internal/tracker"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "entries.jsonl")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// describe returns the entries as "<id> <description> <running|stopped>"
+// lines.
+func describe(entries []tracker.Entry) string {
+	var lines []string
+	for _, entry := range entries {
+		state := "stopped"
+		if entry.Running() {
+			state = "running"
+		}
+		lines = append(lines, string(entry.ID)+" "+entry.Description+" "+state)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		entries string
+		current tracker.ID
+		err     string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "last line wins",
+			content: `{"id":"1","description":"first","start":"2026-03-02T09:00:00Z"}
+{"id":"1","description":"first","start":"2026-03-02T09:00:00Z","stop":"2026-03-02T10:00:00Z"}
+
+{"id":"2","description":"second","start":"2026-03-02T10:00:00Z"}
+{"id":"2","description":"renamed","start":"2026-03-02T10:00:00Z"}
+`,
+			entries: "1 first stopped\n2 renamed running",
+			current: "2",
+		},
+		{
+			name: "numeric ids",
+			content: `{"id":7,"description":"toggl","start":"2026-03-02T09:00:00Z","stop":"2026-03-02T10:00:00Z"}
+`,
+			entries: "7 toggl stopped",
+		},
+		{
+			name: "invalid line",
+			content: `{"id":"1","description":"first","start":"2026-03-02T09:00:00Z"}
+{"id":
+`,
+			err: "entries.jsonl:2: unexpected end of JSON input",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tr, err := New(writeFile(t, test.content), nil)
+			if test.err != "" {
+				if err == nil || !strings.HasSuffix(err.Error(), test.err) {
+					t.Fatalf("error %v, expected %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries, err := tr.Entries(context.Background(), time.Time{}, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := describe(entries); got != test.entries {
+				t.Errorf("entries:\n%s\nexpected:\n%s", got, test.entries)
+			}
+			current, err := tr.Current(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if current.ID != test.current {
+				t.Errorf("current %q, expected %q", current.ID, test.current)
+			}
+		})
+	}
+}
+
+func TestStartStopsRunning(t *testing.T) {
+	path := writeFile(t, "")
+	tr, err := New(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	first, err := tr.Start(ctx, tracker.Entry{Description: "first", Start: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := tr.Start(ctx, tracker.Entry{Description: "second", Start: start.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != "1" || second.ID != "2" {
+		t.Errorf("ids %q %q, expected 1 2", first.ID, second.ID)
+	}
+	// an other bar reads the same file
+	other, err := New(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := other.Entries(ctx, start, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := describe(entries); got != "1 first stopped\n2 second running" {
+		t.Fatalf("entries:\n%s", got)
+	}
+	if d := entries[0].Duration(); d != time.Hour {
+		t.Errorf("first stopped after %s, expected 1h", d)
+	}
+	stop := start.Add(90 * time.Minute)
+	if _, err := other.Stop(ctx, tracker.Entry{ID: second.ID, Stop: &stop}); err != nil {
+		t.Fatal(err)
+	}
+	if current, err := tr.Current(ctx); err != nil || current.ID != "" {
+		t.Errorf("current %q %v after stop", current.ID, err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	local := time.Local
+	time.Local = time.UTC
+	defer func() { time.Local = local }()
+	content := `{"id":"1","description":"Review, \"tests\"","pid":"Work","tid":"Review","tags":["a","b"],"start":"2026-03-02T09:00:00Z","stop":"2026-03-02T10:30:15Z"}
+{"id":"2","description":"running","pid":"Work","start":"2026-03-02T11:00:00Z"}
+`
+	tests := []struct {
+		format   string
+		expected string
+		err      string
+	}{
+		{
+			format: FormatToggl,
+			expected: "Email,Project,Task,Description,Start date,Start time,Duration,Tags\n" +
+				"me@example.com,Work,Review,\"Review, \"\"tests\"\"\",2026-03-02,09:00:00,01:30:15,\"a, b\"\n",
+		},
+		{
+			format: FormatClockify,
+			expected: "Project,Description,Task,Email,Tags,Start Date,Start Time,End Date,End Time,Duration (h)\n" +
+				"Work,\"Review, \"\"tests\"\"\",Review,me@example.com,\"a, b\",2026-03-02,09:00:00,2026-03-02,10:30:15,01:30:15\n",
+		},
+		{
+			format: "harvest",
+			err:    "unknown export format: `harvest`",
+		},
+	}
+	tr, err := New(writeFile(t, content), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var out strings.Builder
+			err := tr.Export(&out, test.format, "me@example.com")
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("error %v, expected %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != test.expected {
+				t.Errorf("export:\n%s\nexpected:\n%s", out.String(), test.expected)
+			}
+		})
+	}
+}
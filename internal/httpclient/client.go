@@ -0,0 +1,276 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ak-Army/xlog"
+)
+
+// DefaultRetries is the Retries of the clients without their own retry
+// policy.
+const DefaultRetries = -1
+
+type Config struct {
+	BaseURL string
+	// Timeout of one attempt
+	Timeout time.Duration
+	// Number of retries after the first attempt, 0 disables them and
+	// DefaultRetries retries 3 times
+	Retries int
+	// First delay between two attempts, doubled after every retry
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Auth adds the credentials to the request
+	Auth func(req *http.Request)
+	Log  xlog.Logger
+}
+
+type Client struct {
+	client *http.Client
+	config Config
+}
+
+func New(config Config) *Client {
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.Retries < 0 {
+		config.Retries = 3
+	}
+	if config.Backoff == 0 {
+		config.Backoff = 500 * time.Millisecond
+	}
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = time.Minute
+	}
+	if config.Auth == nil {
+		config.Auth = func(*http.Request) {}
+	}
+	if config.Log == nil {
+		config.Log = xlog.NopLogger
+	}
+	return &Client{
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				TLSHandshakeTimeout: config.Timeout,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			Timeout: config.Timeout,
+		},
+		config: config,
+	}
+}
+
+// APIError is returned for the responses with a non 2xx status code.
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       []byte
+	// Delay requested by the server with Retry-After
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	body := strings.TrimSpace(string(e.Body))
+	if len(body) > 200 {
+		body = body[:200] + "..."
+	}
+	msg := fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if body != "" {
+		msg += ": " + body
+	}
+	return msg
+}
+
+// Temporary reports whether the request can succeed when retried.
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// ContentTypeError is a successful response which is not JSON, eg: the login
+// page of a captive portal.
+type ContentTypeError struct {
+	Method      string
+	URL         string
+	ContentType string
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("%s %s: unexpected content type: %s", e.Method, e.URL, e.ContentType)
+}
+
+// Temporary reports true, the API is expected to answer once the network is
+// usable again.
+func (e *ContentTypeError) Temporary() bool {
+	return true
+}
+
+// Temporary reports whether err is a network failure or a response worth
+// retrying later, the other errors are rejections by the API, invalid
+// certificates and invalid URLs.
+func Temporary(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary()
+	}
+	var typeErr *ContentTypeError
+	if errors.As(err, &typeErr) {
+		return typeErr.Temporary()
+	}
+	if certificateError(err) {
+		return false
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Op != "parse"
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+func certificateError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	var verification *tls.CertificateVerificationError
+	return errors.As(err, &unknownAuthority) || errors.As(err, &invalid) ||
+		errors.As(err, &hostname) || errors.As(err, &verification)
+}
+
+// Do sends param as JSON to the endpoint and returns the JSON response. The
+// failed requests are retried with backoff until ctx is done, the POST
+// requests only when the server asked for it with 429.
+func (c *Client) Do(ctx context.Context, method string, endpoint string, param interface{}) ([]byte, error) {
+	var body []byte
+	if param != nil {
+		var err error
+		if body, err = json.Marshal(param); err != nil {
+			return nil, err
+		}
+	}
+	backoff := c.config.Backoff
+	for attempt := 0; ; attempt++ {
+		response, err := c.do(ctx, method, endpoint, body)
+		if err == nil || attempt >= c.config.Retries || !c.retryable(method, err) {
+			return response, err
+		}
+		delay := backoff
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > delay {
+			delay = apiErr.RetryAfter
+		}
+		if delay > c.config.MaxBackoff {
+			delay = c.config.MaxBackoff
+		}
+		c.config.Log.Warnf("Retrying in %s: %s", delay, err)
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) retryable(method string, err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if method == http.MethodPost {
+			return apiErr.StatusCode == http.StatusTooManyRequests
+		}
+		return apiErr.Temporary()
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return Temporary(err) && method != http.MethodPost
+}
+
+func (c *Client) do(ctx context.Context, method string, endpoint string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.config.BaseURL+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	c.config.Auth(req)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	start := time.Now()
+	res, err := c.client.Do(req)
+	if err != nil {
+		// the error contains the URL
+		if urlErr, ok := err.(*url.Error); ok {
+			urlErr.URL = Redact(req.URL)
+		}
+		return nil, err
+	}
+	defer res.Body.Close()
+	response, err := io.ReadAll(res.Body)
+	c.config.Log.Debugf("%s %s: %d in %s", method, Redact(req.URL), res.StatusCode, time.Since(start))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, &APIError{
+			Method:     method,
+			URL:        Redact(req.URL),
+			StatusCode: res.StatusCode,
+			Body:       response,
+			RetryAfter: retryAfter(res.Header.Get("Retry-After")),
+		}
+	}
+	if len(response) > 0 && !strings.Contains(res.Header.Get("Content-Type"), "application/json") {
+		return nil, &ContentTypeError{
+			Method:      method,
+			URL:         Redact(req.URL),
+			ContentType: res.Header.Get("Content-Type"),
+		}
+	}
+	return response, nil
+}
+
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+var secretParams = []string{"token", "access_token", "api_token", "apikey", "api_key", "key", "password"}
+
+// Redact returns the URL without credentials, safe to be logged.
+func Redact(u *url.URL) string {
+	redacted := *u
+	if redacted.User != nil {
+		redacted.User = url.User("REDACTED")
+	}
+	query := redacted.Query()
+	for _, param := range secretParams {
+		for name := range query {
+			if strings.EqualFold(name, param) {
+				query.Set(name, "REDACTED")
+			}
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
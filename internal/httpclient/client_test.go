@@ -0,0 +1,56 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTemporary(t *testing.T) {
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}))
+	defer tlsServer.Close()
+	portal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html>login</html>"))
+	}))
+	defer portal.Close()
+	status := func(code int) string {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+		}))
+		t.Cleanup(server.Close)
+		return server.URL
+	}
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		name      string
+		url       string
+		temporary bool
+	}{
+		{"unknown certificate authority", tlsServer.URL, false},
+		{"captive portal", portal.URL, true},
+		{"connection refused", closed.URL, true},
+		{"invalid url", "http://[::1", false},
+		{"not found", status(http.StatusNotFound), false},
+		{"too many requests", status(http.StatusTooManyRequests), true},
+		{"server error", status(http.StatusBadGateway), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := New(Config{BaseURL: tt.url, Retries: 0})
+			_, err := client.Do(context.Background(), http.MethodGet, "/", nil)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if got := Temporary(err); got != tt.temporary {
+				t.Errorf("Temporary(%q) = %v, expected: %v", err, got, tt.temporary)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package launcher
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/Ak-Army/xlog"
+)
+
+const urlPlaceholder = "{url}"
+
+var ErrNoOpener = errors.New("no URL opener found, install xdg-open")
+
+// Openers tried in order for the URLs without a scheme handler.
+var defaultOpeners = [][]string{
+	{"xdg-open"},
+	{"brave-browser"},
+	{"google-chrome"},
+	{"firefox"},
+	{"open"},
+}
+
+type Config struct {
+	// Command by URL scheme, eg: "zoommtg": ["zoom", "--url={url}"], the URL
+	// is appended when the command has no {url} placeholder
+	Handlers map[string][]string `config:"handlers" json:"handlers"`
+	// Commands tried in order for the other URLs
+	Openers [][]string `config:"openers" json:"openers"`
+}
+
+type Launcher struct {
+	config   Config
+	log      xlog.Logger
+	lookPath func(string) (string, error)
+}
+
+func New(config Config, log xlog.Logger) *Launcher {
+	if len(config.Openers) == 0 {
+		config.Openers = defaultOpeners
+	}
+	return &Launcher{
+		config:   config,
+		log:      log,
+		lookPath: exec.LookPath,
+	}
+}
+
+// Open opens the URL with the handler of its scheme or the first opener
+// found, without waiting for it.
+func (l *Launcher) Open(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if handler, ok := l.config.Handlers[u.Scheme]; ok && len(handler) > 0 {
+		return l.Run(withURL(handler, rawURL)...)
+	}
+	for _, opener := range l.config.Openers {
+		if len(opener) == 0 {
+			continue
+		}
+		if _, err := l.lookPath(opener[0]); err == nil {
+			return l.Run(withURL(opener, rawURL)...)
+		}
+	}
+	return ErrNoOpener
+}
+
+// Run starts the command in a new session and returns after it started, the
+// failures after that are logged.
+func (l *Launcher) Run(argv ...string) error {
+	return l.RunEnv(nil, argv...)
+}
+
+// RunEnv is Run with extra environment variables, eg: "NAME=value".
+func (l *Launcher) RunEnv(env []string, argv ...string) error {
+	if len(argv) == 0 {
+		return errors.New("empty command")
+	}
+	cmd := exec.Command(argv[0], argv[1:]...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("unable to start %s: %s", argv[0], err)
+	}
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			l.log.Warnf("Launch %q: %s %s", argv, err, strings.TrimSpace(stderr.String()))
+		}
+	}()
+	return nil
+}
+
+func withURL(command []string, rawURL string) []string {
+	argv := make([]string, 0, len(command)+1)
+	var replaced bool
+	for _, arg := range command {
+		if strings.Contains(arg, urlPlaceholder) {
+			arg = strings.ReplaceAll(arg, urlPlaceholder, rawURL)
+			replaced = true
+		}
+		argv = append(argv, arg)
+	}
+	if !replaced {
+		argv = append(argv, rawURL)
+	}
+	return argv
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	dbusDest   = "org.freedesktop.Notifications"
+	dbusPath   = "/org/freedesktop/Notifications"
+	dbusNotify = dbusDest + ".Notify"
+)
+
+// DBus sends freedesktop notifications, notifications with the same key
+// replace the previous one on the screen.
+type DBus struct {
+	mu      sync.Mutex
+	address string
+	appName string
+	conn    *dbus.Conn
+	ids     map[string]uint32
+}
+
+func NewDBus(address string, appName string) *DBus {
+	return &DBus{
+		address: address,
+		appName: appName,
+		ids:     make(map[string]uint32),
+	}
+}
+
+func (d *DBus) Send(n Notification) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.conn == nil {
+		var err error
+		if d.address == "" {
+			d.conn, err = dbus.ConnectSessionBus()
+		} else {
+			d.conn, err = dbus.Connect(d.address)
+		}
+		if err != nil {
+			d.conn = nil
+			return err
+		}
+	}
+	timeout := int32(-1)
+	if n.Timeout > 0 {
+		timeout = int32(n.Timeout.Milliseconds())
+	}
+	hints := map[string]dbus.Variant{
+		"urgency": dbus.MakeVariant(byte(n.Urgency)),
+	}
+	var id uint32
+	err := d.conn.Object(dbusDest, dbusPath).Call(dbusNotify, 0,
+		d.appName, d.ids[n.Key], n.Icon, n.Summary, n.Body, []string{}, hints, timeout).Store(&id)
+	if err != nil {
+		d.conn.Close()
+		d.conn = nil
+		return err
+	}
+	if n.Key != "" {
+		d.ids[n.Key] = id
+	}
+	return nil
+}
+
+func (d *DBus) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.conn == nil {
+		return nil
+	}
+	err := d.conn.Close()
+	d.conn = nil
+	return err
+}
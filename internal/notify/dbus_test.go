@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"bufio"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// notificationServer is the notification daemon on the private bus.
+type notificationServer struct {
+	mu       sync.Mutex
+	replaces []uint32
+	lastID   uint32
+}
+
+func (s *notificationServer) Notify(app string, replaces uint32, icon string, summary string, body string,
+	actions []string, hints map[string]dbus.Variant, timeout int32) (uint32, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replaces = append(s.replaces, replaces)
+	if replaces != 0 {
+		return replaces, nil
+	}
+	s.lastID++
+	return s.lastID, nil
+}
+
+// privateBus starts a dbus-daemon and returns its address.
+func privateBus(t *testing.T) string {
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not found")
+	}
+	cmd := exec.Command(daemon, "--session", "--nofork", "--print-address=1")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(address)
+}
+
+func TestDBus(t *testing.T) {
+	address := privateBus(t)
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	server := &notificationServer{}
+	if err := conn.Export(server, dbusPath, dbusDest); err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := conn.RequestName(dbusDest, dbus.NameFlagDoNotQueue); err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("unable to own %s: %v %v", dbusDest, reply, err)
+	}
+
+	sender := NewDBus(address, "test")
+	defer sender.Close()
+	for _, n := range []Notification{
+		{Key: "disk", Summary: "disk"},
+		{Key: "timer", Summary: "timer"},
+		{Key: "disk", Summary: "disk again"},
+		{Summary: "no key"},
+	} {
+		if err := sender.Send(n); err != nil {
+			t.Fatalf("%s: %s", n.Summary, err)
+		}
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	expected := []uint32{0, 0, 1, 0}
+	if len(server.replaces) != len(expected) {
+		t.Fatalf("replaces ids: %v, expected %v", server.replaces, expected)
+	}
+	for i := range expected {
+		if server.replaces[i] != expected[i] {
+			t.Fatalf("replaces ids: %v, expected %v", server.replaces, expected)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type Urgency byte
+
+const (
+	UrgencyLow      Urgency = 0
+	UrgencyNormal   Urgency = 1
+	UrgencyCritical Urgency = 2
+)
+
+var (
+	ErrDuplicate   = errors.New("duplicated notification")
+	ErrRateLimited = errors.New("notification rate limit reached")
+)
+
+type Notification struct {
+	// Notifications with the same key are de-duplicated and replace each other
+	Key     string
+	Summary string
+	Body    string
+	Icon    string
+	Urgency Urgency
+	Timeout time.Duration
+	Time    time.Time
+}
+
+type Sender interface {
+	Send(n Notification) error
+}
+
+type Config struct {
+	// D-Bus address, the session bus is used when empty
+	Address string `config:"address" json:"address"`
+	// Maximum number of notifications per minute
+	RateLimit int `config:"rateLimit" json:"rateLimit"`
+	// Seconds while a notification with the same key is not sent again
+	Dedup int `config:"dedup" json:"dedup"`
+	// Number of recent notifications kept
+	History int `config:"history" json:"history"`
+}
+
+type Notifier struct {
+	mu       sync.Mutex
+	sender   Sender
+	config   Config
+	sent     []time.Time
+	lastSent map[string]time.Time
+	history  []Notification
+	now      func() time.Time
+}
+
+func New(sender Sender, config Config) *Notifier {
+	if config.RateLimit == 0 {
+		config.RateLimit = 5
+	}
+	if config.Dedup == 0 {
+		config.Dedup = 3600
+	}
+	if config.History == 0 {
+		config.History = 20
+	}
+	return &Notifier{
+		sender:   sender,
+		config:   config,
+		lastSent: make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+// SetClock replaces the time source of the rate limit and the de-duplication.
+func (n *Notifier) SetClock(now func() time.Time) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.now = now
+}
+
+func (n *Notifier) Notify(notification Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	now := n.now()
+	if notification.Key != "" {
+		if last, ok := n.lastSent[notification.Key]; ok &&
+			now.Sub(last) < time.Duration(n.config.Dedup)*time.Second {
+			return ErrDuplicate
+		}
+	}
+	var sent []time.Time
+	for _, t := range n.sent {
+		if now.Sub(t) < time.Minute {
+			sent = append(sent, t)
+		}
+	}
+	n.sent = sent
+	if len(n.sent) >= n.config.RateLimit {
+		return ErrRateLimited
+	}
+	notification.Time = now
+	if err := n.sender.Send(notification); err != nil {
+		return err
+	}
+	n.sent = append(n.sent, now)
+	if notification.Key != "" {
+		n.lastSent[notification.Key] = now
+	}
+	n.history = append(n.history, notification)
+	if len(n.history) > n.config.History {
+		n.history = n.history[len(n.history)-n.config.History:]
+	}
+	return nil
+}
+
+// History returns the recently sent notifications, oldest first.
+func (n *Notifier) History() []Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	history := make([]Notification, len(n.history))
+	copy(history, n.history)
+	return history
+}
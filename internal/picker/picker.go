@@ -0,0 +1,72 @@
+package picker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+var (
+	ErrCancelled = errors.New("nothing picked")
+	ErrNoPicker  = errors.New("no picker found, install rofi or dmenu")
+)
+
+// Pickers tried in order when no command is configured, they read the items
+// on stdin and print the picked one.
+var defaultPickers = [][]string{
+	{"rofi", "-dmenu", "-i", "-matching", "fuzzy", "-p"},
+	{"wofi", "--dmenu", "--insensitive", "--prompt"},
+	{"dmenu", "-i", "-p"},
+}
+
+// Command returns the configured picker command or the first one found,
+// with the prompt appended to the default ones.
+func Command(command []string, prompt string) ([]string, error) {
+	if len(command) > 0 {
+		return command, nil
+	}
+	for _, picker := range defaultPickers {
+		if _, err := exec.LookPath(picker[0]); err == nil {
+			return append(append([]string{}, picker...), prompt), nil
+		}
+	}
+	return nil, ErrNoPicker
+}
+
+// Pick shows the items with the picker and returns the index of the picked
+// one, ErrCancelled when the picker was closed or ctx is done.
+func Pick(ctx context.Context, command []string, items []string) (int, error) {
+	if len(command) == 0 {
+		return 0, ErrNoPicker
+	}
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdin = strings.NewReader(strings.Join(items, "\n"))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return 0, ErrCancelled
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return 0, ErrCancelled
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return 0, fmt.Errorf("%s: %s", command[0], msg)
+		}
+		return 0, fmt.Errorf("%s: %s", command[0], err)
+	}
+	picked := strings.TrimRight(string(out), "\n")
+	if picked == "" {
+		return 0, ErrCancelled
+	}
+	for i, item := range items {
+		if item == picked {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown item picked: %q", picked)
+}
@@ -0,0 +1,146 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Version of the state file, files written by a newer version are not read
+// nor overwritten.
+const Version = 1
+
+type Config struct {
+	// State file, $XDG_STATE_HOME/i3barfeeder/state.json when empty
+	Path string `config:"path" json:"path"`
+}
+
+type file struct {
+	Version int               `json:"version"`
+	Blocks  map[string]*entry `json:"blocks"`
+}
+
+type entry struct {
+	Module string          `json:"module"`
+	State  json.RawMessage `json:"state"`
+}
+
+type Store struct {
+	mu     sync.Mutex
+	path   string
+	blocks map[string]*entry
+	saved  []byte
+}
+
+// DefaultPath returns the state file under the XDG state directory.
+func DefaultPath() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = os.TempDir()
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "i3barfeeder", "state.json")
+}
+
+// Open reads the state file, a missing file is an empty state.
+func Open(path string) (*Store, error) {
+	if path == "" {
+		path = DefaultPath()
+	}
+	s := &Store{
+		path:   path,
+		blocks: map[string]*entry{},
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %s", path, err)
+	}
+	if f.Version > Version {
+		return nil, fmt.Errorf("unsupported state file version %d: %s", f.Version, path)
+	}
+	if f.Blocks != nil {
+		s.blocks = f.Blocks
+	}
+	s.saved = data
+	return s, nil
+}
+
+// Get returns the state saved for the block, or nil when the block had
+// another module.
+func (s *Store) Get(block string, module string) json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.blocks[block]
+	if !ok || e.Module != module {
+		return nil
+	}
+	return e.State
+}
+
+func (s *Store) Set(block string, module string, state json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blocks[block] = &entry{
+		Module: module,
+		State:  state,
+	}
+}
+
+// Save writes the state file atomically when it has changed.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := json.MarshalIndent(file{
+		Version: Version,
+		Blocks:  s.blocks,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, s.saved) {
+		return nil
+	}
+	if err := WriteFile(s.path, data); err != nil {
+		return err
+	}
+	s.saved = data
+	return nil
+}
+
+// WriteFile replaces the file atomically, readers see either the old or the
+// new content.
+func WriteFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,114 @@
+package tickets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/internal/httpclient"
+)
+
+type TargetProcess struct {
+	http   *httpclient.Client
+	userID int64
+}
+
+func NewTargetProcess(apiURL string, token string, log xlog.Logger) *TargetProcess {
+	return &TargetProcess{
+		http: httpclient.New(httpclient.Config{
+			BaseURL: strings.TrimRight(apiURL, "/"),
+			Retries: httpclient.DefaultRetries,
+			Auth: func(req *http.Request) {
+				query := req.URL.Query()
+				query.Set("access_token", token)
+				query.Set("format", "json")
+				req.URL.RawQuery = query.Encode()
+			},
+			Log: log,
+		}),
+	}
+}
+
+func (t *TargetProcess) Name() string {
+	return "TargetProcess"
+}
+
+type tpEntity struct {
+	ID   int64  `json:"Id"`
+	Name string `json:"Name,omitempty"`
+}
+
+func (t *TargetProcess) loggedUser(ctx context.Context) (int64, error) {
+	if t.userID != 0 {
+		return t.userID, nil
+	}
+	res, err := t.http.Do(ctx, http.MethodGet, "/api/v1/Users/LoggedUser", nil)
+	if err != nil {
+		return 0, err
+	}
+	var user tpEntity
+	if err := json.Unmarshal(res, &user); err != nil {
+		return 0, err
+	}
+	t.userID = user.ID
+	return t.userID, nil
+}
+
+func (t *TargetProcess) Tickets(ctx context.Context) ([]Ticket, error) {
+	userID, err := t.loggedUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	where := fmt.Sprintf("(EntityState.IsFinal eq 'false') and (AssignedUser.Id eq %d)", userID)
+	res, err := t.http.Do(ctx, http.MethodGet,
+		"/api/v1/Assignables?take=100&include=[Id,Name]&where="+url.QueryEscape(where), nil)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Items []tpEntity `json:"Items"`
+	}
+	if err := json.Unmarshal(res, &response); err != nil {
+		return nil, err
+	}
+	tickets := make([]Ticket, 0, len(response.Items))
+	for _, item := range response.Items {
+		tickets = append(tickets, Ticket{
+			Name: item.Name,
+			TPID: fmt.Sprintf("#%d", item.ID),
+		})
+	}
+	return tickets, nil
+}
+
+type tpTime struct {
+	Description string   `json:"Description,omitempty"`
+	Spent       float64  `json:"Spent"`
+	Date        string   `json:"Date"`
+	Assignable  tpEntity `json:"Assignable"`
+}
+
+func (t *TargetProcess) LogTime(ctx context.Context, ticket Ticket, start time.Time, spent time.Duration, text string) error {
+	id, err := strconv.ParseInt(strings.TrimPrefix(ticket.TPID, "#"), 10, 64)
+	if err != nil {
+		// not a TargetProcess ticket
+		return nil
+	}
+	if spent < time.Minute {
+		return nil
+	}
+	_, err = t.http.Do(ctx, http.MethodPost, "/api/v1/Times", tpTime{
+		Description: text,
+		Spent:       spent.Hours(),
+		Date:        start.Format(time.RFC3339),
+		Assignable:  tpEntity{ID: id},
+	})
+	return err
+}
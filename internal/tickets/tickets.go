@@ -0,0 +1,25 @@
+package tickets
+
+import (
+	"context"
+	"time"
+)
+
+// Ticket is an issue time can be tracked on.
+type Ticket struct {
+	Name string
+	// YouTrack issue ID, eg: DOTO-2
+	YTID string
+	// TargetProcess entity ID, eg: #1799
+	TPID string
+}
+
+// Provider lists the tickets of the user from an issue tracker.
+type Provider interface {
+	Name() string
+	// Tickets returns the assigned or in progress tickets
+	Tickets(ctx context.Context) ([]Ticket, error)
+	// LogTime posts the time spent on the ticket, tickets unknown by the
+	// provider are ignored
+	LogTime(ctx context.Context, ticket Ticket, start time.Time, spent time.Duration, text string) error
+}
@@ -0,0 +1,93 @@
+package tickets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/internal/httpclient"
+)
+
+const DefaultYouTrackQuery = "for: me #Unresolved"
+
+type YouTrack struct {
+	http  *httpclient.Client
+	query string
+}
+
+func NewYouTrack(apiURL string, token string, query string, log xlog.Logger) *YouTrack {
+	if query == "" {
+		query = DefaultYouTrackQuery
+	}
+	return &YouTrack{
+		http: httpclient.New(httpclient.Config{
+			BaseURL: strings.TrimRight(apiURL, "/"),
+			Retries: httpclient.DefaultRetries,
+			Auth: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+token)
+			},
+			Log: log,
+		}),
+		query: query,
+	}
+}
+
+func (y *YouTrack) Name() string {
+	return "YouTrack"
+}
+
+type youTrackIssue struct {
+	ID      string `json:"idReadable"`
+	Summary string `json:"summary"`
+}
+
+func (y *YouTrack) Tickets(ctx context.Context) ([]Ticket, error) {
+	res, err := y.http.Do(ctx, http.MethodGet,
+		"/api/issues?fields=idReadable,summary&$top=100&query="+url.QueryEscape(y.query), nil)
+	if err != nil {
+		return nil, err
+	}
+	var issues []youTrackIssue
+	if err := json.Unmarshal(res, &issues); err != nil {
+		return nil, err
+	}
+	tickets := make([]Ticket, 0, len(issues))
+	for _, issue := range issues {
+		tickets = append(tickets, Ticket{
+			Name: issue.Summary,
+			YTID: issue.ID,
+		})
+	}
+	return tickets, nil
+}
+
+type youTrackWorkItem struct {
+	Date     int64 `json:"date"`
+	Duration struct {
+		Minutes int `json:"minutes"`
+	} `json:"duration"`
+	Text string `json:"text,omitempty"`
+}
+
+func (y *YouTrack) LogTime(ctx context.Context, ticket Ticket, start time.Time, spent time.Duration, text string) error {
+	if ticket.YTID == "" {
+		return nil
+	}
+	item := youTrackWorkItem{
+		Date: start.UnixNano() / int64(time.Millisecond),
+		Text: text,
+	}
+	item.Duration.Minutes = int(spent.Round(time.Minute) / time.Minute)
+	if item.Duration.Minutes == 0 {
+		return nil
+	}
+	_, err := y.http.Do(ctx, http.MethodPost,
+		fmt.Sprintf("/api/issues/%s/timeTracking/workItems", url.PathEscape(ticket.YTID)), item)
+	return err
+}
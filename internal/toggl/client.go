@@ -1,64 +1,31 @@
 package toggl
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"io/ioutil"
+	"context"
 	"net/http"
-	"strings"
+
+	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/internal/httpclient"
 )
 
 type Client struct {
-	client    *http.Client
-	transport *http.Transport
-	baseUrl   string
-	apiToken  string
+	http *httpclient.Client
 }
 
-func NewClient(apiToken string) Client {
-	transport := &http.Transport{}
-	baseUrl := "https://api.track.toggl.com/api/v9"
-
+func NewClient(apiToken string, log xlog.Logger) Client {
 	return Client{
-		client:    &http.Client{Transport: transport},
-		transport: transport,
-		baseUrl:   baseUrl,
-		apiToken:  apiToken,
+		http: httpclient.New(httpclient.Config{
+			BaseURL: "https://api.track.toggl.com/api/v9",
+			Retries: httpclient.DefaultRetries,
+			Auth: func(req *http.Request) {
+				req.SetBasicAuth(apiToken, "api_token")
+			},
+			Log: log,
+		}),
 	}
 }
-func (c Client) request(method string, endpoint string, param interface{}) (response []byte, err error) {
-	var bodyText []byte
-	if param != nil {
-		bodyText, err = json.Marshal(param)
-		if err != nil {
-			return
-		}
-	}
 
-	req, err := http.NewRequest(method, c.baseUrl+endpoint, bytes.NewReader(bodyText))
-	if err != nil {
-		return
-	}
-	req.SetBasicAuth(c.apiToken, "api_token")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-
-	//xlog.Debugf("Requesting %s %s", method, c.baseUrl+endpoint)
-	res, err := c.client.Do(req)
-	if err != nil {
-		return
-	}
-	defer res.Body.Close()
-	contentType := res.Header.Get("content-type")
-	if !(res.StatusCode >= 200 && res.StatusCode < 300) {
-		err = fmt.Errorf("response wrong status code: %d", res.StatusCode)
-		response, _ = ioutil.ReadAll(res.Body)
-	} else if strings.Contains(contentType, "application/json") {
-		response, err = ioutil.ReadAll(res.Body)
-	} else {
-		err = errors.New("response wrong content type")
-	}
-	return
+func (c Client) request(ctx context.Context, method string, endpoint string, param interface{}) ([]byte, error) {
+	return c.http.Do(ctx, method, endpoint, param)
 }
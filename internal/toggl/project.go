@@ -1,6 +1,7 @@
 package toggl
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
@@ -71,9 +72,9 @@ func (t Tasks) FindByName(name string) *Task {
 	return nil
 }
 
-func (c *Client) GetWorkspaceProjects(wid int64) (Projects, error) {
+func (c *Client) GetWorkspaceProjects(ctx context.Context, wid int64) (Projects, error) {
 	var projects Projects
-	res, err := c.request("GET", fmt.Sprintf("/workspaces/%d/projects", wid), nil)
+	res, err := c.request(ctx, "GET", fmt.Sprintf("/workspaces/%d/projects", wid), nil)
 	if err != nil {
 		return projects, err
 	}
@@ -81,12 +82,28 @@ func (c *Client) GetWorkspaceProjects(wid int64) (Projects, error) {
 	return projects, err
 }
 
-func (c *Client) GetProjectTasks(wid int64, pid int64) (Tasks, error) {
+func (c *Client) GetProjectTasks(ctx context.Context, wid int64, pid int64) (Tasks, error) {
 	var tasks Tasks
-	res, err := c.request("GET", fmt.Sprintf("/workspaces/%d/projects/%d/tasks", wid, pid), nil)
+	res, err := c.request(ctx, "GET", fmt.Sprintf("/workspaces/%d/projects/%d/tasks", wid, pid), nil)
 	if err != nil {
 		return tasks, err
 	}
 	err = json.Unmarshal(res, &tasks)
 	return tasks, err
 }
+
+type Tag struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	WID  int64  `json:"workspace_id"`
+}
+
+func (c *Client) GetWorkspaceTags(ctx context.Context, wid int64) ([]Tag, error) {
+	var tags []Tag
+	res, err := c.request(ctx, "GET", fmt.Sprintf("/workspaces/%d/tags", wid), nil)
+	if err != nil {
+		return tags, err
+	}
+	err = json.Unmarshal(res, &tags)
+	return tags, err
+}
@@ -1,6 +1,7 @@
 package toggl
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -32,8 +33,8 @@ type currentResponse struct {
 	Data TimeEntry `json:"data"`
 }
 
-func (c *Client) GetCurrentTimeEntry() (TimeEntry, error) {
-	res, err := c.request("GET", "/me/time_entries/current", nil)
+func (c *Client) GetCurrentTimeEntry(ctx context.Context) (TimeEntry, error) {
+	res, err := c.request(ctx, "GET", "/me/time_entries/current", nil)
 	if err != nil {
 		return TimeEntry{}, err
 	}
@@ -45,10 +46,10 @@ func (c *Client) GetCurrentTimeEntry() (TimeEntry, error) {
 	return response, nil
 }
 
-func (c *Client) StartTimeEntry(timeEntry TimeEntry) (TimeEntry, error) {
-	res, err := c.request("POST", fmt.Sprintf("/workspaces/%d/time_entries", timeEntry.WID), timeEntry)
+func (c *Client) StartTimeEntry(ctx context.Context, timeEntry TimeEntry) (TimeEntry, error) {
+	res, err := c.request(ctx, "POST", fmt.Sprintf("/workspaces/%d/time_entries", timeEntry.WID), timeEntry)
 	if err != nil {
-		xlog.Errorf("Unable to start time entry: %#v", string(res), err)
+		xlog.Errorf("Unable to start time entry: %s", err)
 		return TimeEntry{}, err
 	}
 	var response = TimeEntry{}
@@ -59,8 +60,8 @@ func (c *Client) StartTimeEntry(timeEntry TimeEntry) (TimeEntry, error) {
 	return response, nil
 }
 
-func (c *Client) StopTimeEntry(timeEntry TimeEntry) (TimeEntry, error) {
-	res, err := c.request("PATCH",
+func (c *Client) StopTimeEntry(ctx context.Context, timeEntry TimeEntry) (TimeEntry, error) {
+	res, err := c.request(ctx, "PATCH",
 		fmt.Sprintf("/workspaces/%d/time_entries/%d/stop", timeEntry.WID, timeEntry.ID), nil)
 
 	if err != nil {
@@ -74,9 +75,9 @@ func (c *Client) StopTimeEntry(timeEntry TimeEntry) (TimeEntry, error) {
 	return response, nil
 }
 
-func (c *Client) GetTimeEntry(id int) (TimeEntry, error) {
+func (c *Client) GetTimeEntry(ctx context.Context, id int) (TimeEntry, error) {
 	idString := strconv.Itoa(id)
-	res, err := c.request("GET", "/me/time_entries/"+idString, nil)
+	res, err := c.request(ctx, "GET", "/me/time_entries/"+idString, nil)
 	if err != nil {
 		return TimeEntry{}, err
 	}
@@ -88,7 +89,7 @@ func (c *Client) GetTimeEntry(id int) (TimeEntry, error) {
 	return response, nil
 }
 
-func (c *Client) GetTimeEntries(fromDate time.Time, toDate time.Time) ([]TimeEntry, error) {
+func (c *Client) GetTimeEntries(ctx context.Context, fromDate time.Time, toDate time.Time) ([]TimeEntry, error) {
 	var response []TimeEntry
 	endpoint := "/me/time_entries"
 	if !fromDate.IsZero() {
@@ -99,7 +100,7 @@ func (c *Client) GetTimeEntries(fromDate time.Time, toDate time.Time) ([]TimeEnt
 			endpoint += "&end_date=" + url.QueryEscape(fromDate.Add(24*time.Hour).Format(dateFormatISO8601))
 		}
 	}
-	res, err := c.request("GET", endpoint, nil)
+	res, err := c.request(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return response, err
 	}
@@ -110,12 +111,12 @@ func (c *Client) GetTimeEntries(fromDate time.Time, toDate time.Time) ([]TimeEnt
 	return response, nil
 }
 
-func (c *Client) UpdateTimeEntry(timeEntry TimeEntry) (TimeEntry, error) {
-	res, err := c.request("PUT",
+func (c *Client) UpdateTimeEntry(ctx context.Context, timeEntry TimeEntry) (TimeEntry, error) {
+	res, err := c.request(ctx, "PUT",
 		fmt.Sprintf("/workspaces/%d/time_entries/%d", timeEntry.WID, timeEntry.ID), timeEntry)
 
 	if err != nil {
-		xlog.Errorf("Unable to update time entry: %#v", res, err)
+		xlog.Errorf("Unable to update time entry: %s", err)
 		return TimeEntry{}, err
 	}
 	var response = TimeEntry{}
@@ -0,0 +1,152 @@
+package toggl
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/internal/tracker"
+)
+
+// Tracker is the Toggl backend of the time tracking block, the entries
+// without a workspace are started in the wid workspace.
+type Tracker struct {
+	client Client
+	wid    int64
+}
+
+func NewTracker(apiToken string, wid int64, log xlog.Logger) *Tracker {
+	return &Tracker{
+		client: NewClient(apiToken, log),
+		wid:    wid,
+	}
+}
+
+func (t *Tracker) Name() string {
+	return "toggl"
+}
+
+func (t *Tracker) Current(ctx context.Context) (tracker.Entry, error) {
+	entry, err := t.client.GetCurrentTimeEntry(ctx)
+	return fromTimeEntry(entry), err
+}
+
+func (t *Tracker) Start(ctx context.Context, entry tracker.Entry) (tracker.Entry, error) {
+	timeEntry := t.toTimeEntry(entry)
+	timeEntry.ID = 0
+	timeEntry.CreatedWith = "hunyi"
+	started, err := t.client.StartTimeEntry(ctx, timeEntry)
+	return fromTimeEntry(started), err
+}
+
+func (t *Tracker) Stop(ctx context.Context, entry tracker.Entry) (tracker.Entry, error) {
+	timeEntry := t.toTimeEntry(entry)
+	if entry.Stop == nil {
+		stopped, err := t.client.StopTimeEntry(ctx, timeEntry)
+		return fromTimeEntry(stopped), err
+	}
+	// the stop endpoint stops the entry now
+	stopped, err := t.client.UpdateTimeEntry(ctx, timeEntry)
+	return fromTimeEntry(stopped), err
+}
+
+func (t *Tracker) Update(ctx context.Context, entry tracker.Entry) (tracker.Entry, error) {
+	updated, err := t.client.UpdateTimeEntry(ctx, t.toTimeEntry(entry))
+	return fromTimeEntry(updated), err
+}
+
+func (t *Tracker) Entries(ctx context.Context, from time.Time, to time.Time) ([]tracker.Entry, error) {
+	timeEntries, err := t.client.GetTimeEntries(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]tracker.Entry, 0, len(timeEntries))
+	for _, timeEntry := range timeEntries {
+		entries = append(entries, fromTimeEntry(timeEntry))
+	}
+	return entries, nil
+}
+
+func (t *Tracker) Projects(ctx context.Context) (tracker.Projects, error) {
+	projects, err := t.client.GetWorkspaceProjects(ctx, t.wid)
+	if err != nil {
+		return nil, err
+	}
+	list := make(tracker.Projects, 0, len(projects))
+	for _, p := range projects {
+		list = append(list, &tracker.Project{
+			ID:     tracker.IntID(p.ID),
+			Name:   p.Name,
+			Active: p.Active,
+		})
+	}
+	return list, nil
+}
+
+func (t *Tracker) Tasks(ctx context.Context, project *tracker.Project) (tracker.Tasks, error) {
+	tasks, err := t.client.GetProjectTasks(ctx, t.wid, project.ID.Int())
+	if err != nil {
+		return nil, err
+	}
+	list := make(tracker.Tasks, 0, len(tasks))
+	for _, task := range tasks {
+		list = append(list, &tracker.Task{
+			ID:     tracker.IntID(task.ID),
+			Name:   task.Name,
+			Active: task.Active,
+		})
+	}
+	return list, nil
+}
+
+func (t *Tracker) Tags(ctx context.Context) ([]string, error) {
+	tags, err := t.client.GetWorkspaceTags(ctx, t.wid)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names, nil
+}
+
+func (t *Tracker) toTimeEntry(entry tracker.Entry) TimeEntry {
+	timeEntry := TimeEntry{
+		ID:          entry.ID.Int(),
+		Description: entry.Description,
+		WID:         entry.Workspace.Int(),
+		PID:         entry.ProjectID.Int(),
+		TID:         entry.TaskID.Int(),
+		Start:       entry.Start,
+		Stop:        entry.Stop,
+		Tags:        entry.Tags,
+		Duration:    -1,
+	}
+	if timeEntry.WID == 0 {
+		timeEntry.WID = t.wid
+	}
+	if entry.Stop != nil {
+		timeEntry.Duration = int64(entry.Duration().Seconds())
+	}
+	return timeEntry
+}
+
+func fromTimeEntry(timeEntry TimeEntry) tracker.Entry {
+	entry := tracker.Entry{
+		ID:          tracker.IntID(timeEntry.ID),
+		Workspace:   tracker.IntID(timeEntry.WID),
+		Description: timeEntry.Description,
+		ProjectID:   tracker.IntID(timeEntry.PID),
+		TaskID:      tracker.IntID(timeEntry.TID),
+		Tags:        timeEntry.Tags,
+		Start:       timeEntry.Start,
+		Stop:        timeEntry.Stop,
+	}
+	if entry.Stop == nil && timeEntry.Duration > 0 {
+		stop := timeEntry.Start.Add(time.Duration(timeEntry.Duration) * time.Second)
+		entry.Stop = &stop
+	}
+	return entry
+}
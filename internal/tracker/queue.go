@@ -0,0 +1,189 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/internal/httpclient"
+	"github.com/Ak-Army/i3barfeeder/internal/state"
+)
+
+type ActionKind string
+
+const (
+	ActionStart  ActionKind = "start"
+	ActionStop   ActionKind = "stop"
+	ActionUpdate ActionKind = "update"
+)
+
+// Action is a change of a time entry waiting for the API, Time is when it
+// happened locally.
+type Action struct {
+	Kind  ActionKind `json:"kind"`
+	Time  time.Time  `json:"time"`
+	Entry Entry      `json:"entry"`
+}
+
+// Queue keeps the actions made while the API was unreachable in a file and
+// replays them in order. The entries started offline get a negative local
+// ID until the API assigns the real one.
+type Queue struct {
+	mu      sync.Mutex
+	path    string
+	Actions []Action  `json:"actions"`
+	IDs     map[ID]ID `json:"ids"`
+	LastID  int64     `json:"lastId"`
+}
+
+func OpenQueue(path string) (*Queue, error) {
+	q := &Queue{
+		path: path,
+		IDs:  map[ID]ID{},
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, q); err != nil {
+		return nil, err
+	}
+	if q.IDs == nil {
+		q.IDs = map[ID]ID{}
+	}
+	return q, nil
+}
+
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.Actions)
+}
+
+// LocalID returns a new ID for an entry started offline.
+func (q *Queue) LocalID() ID {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.LastID--
+	return ID(strconv.FormatInt(q.LastID, 10))
+}
+
+// ResolveID returns the real ID of an entry started offline once it is
+// replayed, other IDs are returned unchanged.
+func (q *Queue) ResolveID(id ID) ID {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if real, ok := q.IDs[id]; ok {
+		return real
+	}
+	return id
+}
+
+func (q *Queue) Push(action Action) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.Actions) == 0 {
+		// the replayed IDs were resolved already
+		q.IDs = map[ID]ID{}
+	}
+	q.Actions = append(q.Actions, action)
+	return q.save()
+}
+
+// Replay sends the queued actions in order. It drops the actions rejected by
+// the API with a 4xx status and stops at any other failure, the action is
+// sent again on the next replay. The queue is not locked while an action is
+// sent, new actions can be pushed meanwhile.
+func (q *Queue) Replay(ctx context.Context, t Tracker, log xlog.Logger) error {
+	for {
+		q.mu.Lock()
+		if len(q.Actions) == 0 {
+			q.mu.Unlock()
+			return nil
+		}
+		action := q.Actions[0]
+		entry := action.Entry
+		if id, ok := q.IDs[entry.ID]; ok {
+			entry.ID = id
+		}
+		q.mu.Unlock()
+
+		id, err := send(ctx, t, action.Kind, action.Time, entry)
+		if err != nil && !rejected(err) {
+			return err
+		}
+		if err != nil {
+			log.Warnf("Dropping queued %s of %q: %s", action.Kind, action.Entry.Description, err)
+		}
+		q.mu.Lock()
+		if id != "" && action.Entry.ID.Local() {
+			q.IDs[action.Entry.ID] = id
+		}
+		q.Actions = q.Actions[1:]
+		err = q.save()
+		q.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// rejected reports whether the API refused the action, sending it again
+// would fail the same way.
+func rejected(err error) bool {
+	var apiErr *httpclient.APIError
+	return errors.As(err, &apiErr) && !apiErr.Temporary() &&
+		apiErr.StatusCode >= 400 && apiErr.StatusCode < 500
+}
+
+// send sends an action with the resolved entry, it returns the ID of the
+// started entry.
+func send(ctx context.Context, t Tracker, kind ActionKind, at time.Time, entry Entry) (ID, error) {
+	switch kind {
+	case ActionStart:
+		entry.ID = ""
+		entry.Start = at
+		entry.Stop = nil
+		started, err := t.Start(ctx, entry)
+		return started.ID, err
+	case ActionStop:
+		if entry.ID.Local() {
+			// the start was dropped
+			return "", nil
+		}
+		entry.Stop = &at
+		_, err := t.Stop(ctx, entry)
+		return "", err
+	case ActionUpdate:
+		if entry.ID.Local() {
+			return "", nil
+		}
+		_, err := t.Update(ctx, entry)
+		return "", err
+	}
+	return "", nil
+}
+
+// Save writes the queue to its file.
+func (q *Queue) Save() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.save()
+}
+
+func (q *Queue) save() error {
+	data, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return err
+	}
+	return state.WriteFile(q.path, data)
+}
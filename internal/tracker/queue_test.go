@@ -0,0 +1,128 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/internal/httpclient"
+)
+
+// testTracker fails every start with err.
+type testTracker struct {
+	Tracker
+	err     error
+	started []Entry
+}
+
+func (t *testTracker) Start(ctx context.Context, entry Entry) (Entry, error) {
+	if t.err != nil {
+		return Entry{}, t.err
+	}
+	entry.ID = "1"
+	t.started = append(t.started, entry)
+	return entry, nil
+}
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kept int
+	}{
+		{"sent", nil, 0},
+		{"bad request", &httpclient.APIError{StatusCode: http.StatusBadRequest}, 0},
+		{"too many requests", &httpclient.APIError{StatusCode: http.StatusTooManyRequests}, 1},
+		{"server error", &httpclient.APIError{StatusCode: http.StatusBadGateway}, 1},
+		{"captive portal", &httpclient.ContentTypeError{ContentType: "text/html"}, 1},
+		{"network", &url.Error{Op: "Post", Err: errors.New("connection refused")}, 1},
+		{"unknown", errors.New("unknown"), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "queue.json")
+			q, err := OpenQueue(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = q.Push(Action{Kind: ActionStart, Time: time.Now(), Entry: Entry{ID: q.LocalID(), Description: "offline"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			q.Replay(context.Background(), &testTracker{err: tt.err}, xlog.NopLogger)
+			if q.Len() != tt.kept {
+				t.Errorf("kept %d actions, expected: %d", q.Len(), tt.kept)
+			}
+			saved, err := OpenQueue(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saved.Len() != tt.kept {
+				t.Errorf("saved %d actions, expected: %d", saved.Len(), tt.kept)
+			}
+		})
+	}
+}
+
+// blockingTracker starts the entries once release is closed.
+type blockingTracker struct {
+	Tracker
+	sending chan struct{}
+	release chan struct{}
+	stopped []ID
+}
+
+func (t *blockingTracker) Start(ctx context.Context, entry Entry) (Entry, error) {
+	close(t.sending)
+	<-t.release
+	entry.ID = "1"
+	return entry, nil
+}
+
+func (t *blockingTracker) Stop(ctx context.Context, entry Entry) (Entry, error) {
+	t.stopped = append(t.stopped, entry.ID)
+	return entry, nil
+}
+
+func TestPushWhileReplaying(t *testing.T) {
+	q, err := OpenQueue(filepath.Join(t.TempDir(), "queue.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := q.LocalID()
+	q.Push(Action{Kind: ActionStart, Time: time.Now(), Entry: Entry{ID: local}})
+	tr := &blockingTracker{sending: make(chan struct{}), release: make(chan struct{})}
+	replayed := make(chan error)
+	go func() {
+		replayed <- q.Replay(context.Background(), tr, xlog.NopLogger)
+	}()
+	<-tr.sending
+	pushed := make(chan error)
+	go func() {
+		pushed <- q.Push(Action{Kind: ActionStop, Time: time.Now(), Entry: Entry{ID: local}})
+	}()
+	select {
+	case err := <-pushed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Push is blocked by the replay")
+	}
+	close(tr.release)
+	if err := <-replayed; err != nil {
+		t.Fatal(err)
+	}
+	if q.Len() != 0 {
+		t.Fatalf("%d actions left", q.Len())
+	}
+	if len(tr.stopped) != 1 || tr.stopped[0] != "1" {
+		t.Errorf("stopped: %v, expected the started entry", tr.stopped)
+	}
+}
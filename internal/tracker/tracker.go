@@ -0,0 +1,149 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tracker is a time tracking service, the time tracking block works with
+// any of them.
+type Tracker interface {
+	Name() string
+	// Current returns the running entry, an entry without ID when none runs
+	Current(ctx context.Context) (Entry, error)
+	Start(ctx context.Context, entry Entry) (Entry, error)
+	// Stop stops the entry at entry.Stop, or now when it is not set
+	Stop(ctx context.Context, entry Entry) (Entry, error)
+	Update(ctx context.Context, entry Entry) (Entry, error)
+	// Entries returns the entries started between from and to
+	Entries(ctx context.Context, from time.Time, to time.Time) ([]Entry, error)
+	Projects(ctx context.Context) (Projects, error)
+	Tasks(ctx context.Context, project *Project) (Tasks, error)
+	Tags(ctx context.Context) ([]string, error)
+}
+
+// ID identifies the entries, projects and tasks of a tracker. The numeric
+// IDs of the Toggl queue files are read as strings.
+type ID string
+
+func (id *ID) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '"' {
+		if string(data) == "null" {
+			*id = ""
+			return nil
+		}
+		var n json.Number
+		if err := json.Unmarshal(data, &n); err != nil {
+			return err
+		}
+		*id = ID(n.String())
+		if *id == "0" {
+			*id = ""
+		}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*id = ID(s)
+	return nil
+}
+
+// Local reports whether the ID was given by the queue to an entry started
+// offline.
+func (id ID) Local() bool {
+	return strings.HasPrefix(string(id), "-")
+}
+
+// Int returns the numeric value of the ID, 0 when it is not numeric.
+func (id ID) Int() int64 {
+	n, _ := strconv.ParseInt(string(id), 10, 64)
+	return n
+}
+
+func IntID(n int64) ID {
+	if n == 0 {
+		return ""
+	}
+	return ID(strconv.FormatInt(n, 10))
+}
+
+type Entry struct {
+	ID ID `json:"id,omitempty"`
+	// Workspace of the entry, the tracker's default workspace when empty
+	Workspace   ID         `json:"wid,omitempty"`
+	Description string     `json:"description"`
+	ProjectID   ID         `json:"pid,omitempty"`
+	TaskID      ID         `json:"tid,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	Start       time.Time  `json:"start,omitempty"`
+	Stop        *time.Time `json:"stop,omitempty"`
+}
+
+func (e Entry) Running() bool {
+	return e.ID != "" && e.Stop == nil
+}
+
+func (e Entry) Duration() time.Duration {
+	if e.Stop == nil {
+		return time.Since(e.Start)
+	}
+	return e.Stop.Sub(e.Start)
+}
+
+type Project struct {
+	ID     ID
+	Name   string
+	Active bool
+	// Tasks are loaded on demand, nil until then
+	Tasks Tasks
+}
+
+type Task struct {
+	ID     ID
+	Name   string
+	Active bool
+}
+
+type Projects []*Project
+type Tasks []*Task
+
+func (p Projects) FindById(id ID) *Project {
+	for _, item := range p {
+		if item.ID == id {
+			return item
+		}
+	}
+	return nil
+}
+
+func (p Projects) FindByName(name string) *Project {
+	for _, item := range p {
+		if item.Name == name {
+			return item
+		}
+	}
+	return nil
+}
+
+func (t Tasks) FindById(id ID) *Task {
+	for _, item := range t {
+		if item.ID == id {
+			return item
+		}
+	}
+	return nil
+}
+
+func (t Tasks) FindByName(name string) *Task {
+	for _, item := range t {
+		if item.Name == name {
+			return item
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package worktime
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Formats are the names of the report formats, text is the overtime in
+// minutes per line with the tab separated values for a spreadsheet at the
+// end.
+var Formats = []string{"text", "csv", "markdown", "tsv"}
+
+// Write writes the report of the days in a format.
+func Write(w io.Writer, format string, days []Day) error {
+	switch format {
+	case "", "text":
+		return writeText(w, days)
+	case "csv", "tsv":
+		out := csv.NewWriter(w)
+		if format == "tsv" {
+			out.Comma = '\t'
+		}
+		out.Write([]string{"Date", "Weekday", "Worked", "Expected", "Balance"})
+		for _, day := range days {
+			out.Write(row(day))
+		}
+		out.Write([]string{"Total", "", FormatHours(worked(days)), FormatHours(expected(days)), FormatHours(Balance(days))})
+		out.Flush()
+		return out.Error()
+	case "markdown":
+		fmt.Fprintln(w, "| Date | Weekday | Worked | Expected | Balance |")
+		fmt.Fprintln(w, "|------|---------|-------:|---------:|--------:|")
+		for _, day := range days {
+			fmt.Fprintf(w, "| %s |\n", strings.Join(row(day), " | "))
+		}
+		_, err := fmt.Fprintf(w, "| **Total** | | **%s** | **%s** | **%s** |\n",
+			FormatHours(worked(days)), FormatHours(expected(days)), FormatHours(Balance(days)))
+		return err
+	}
+	return fmt.Errorf("unknown report format: `%s`", format)
+}
+
+func writeText(w io.Writer, days []Day) error {
+	var output []string
+	for _, day := range days {
+		sum := int64(day.Balance() / time.Minute)
+		fmt.Fprintf(w, "%s %d\n", day.Date.Format(dateFormat), sum)
+		if day.Worked != 0 || day.Expected != 0 {
+			output = append(output, fmt.Sprintf("%d", sum))
+		} else {
+			output = append(output, " ")
+		}
+	}
+	_, err := io.WriteString(w, strings.Join(output, "\t"))
+	return err
+}
+
+func row(day Day) []string {
+	return []string{
+		day.Date.Format(dateFormat),
+		day.Date.Weekday().String()[:3],
+		FormatHours(day.Worked),
+		FormatHours(day.Expected),
+		FormatHours(day.Balance()),
+	}
+}
+
+func worked(days []Day) time.Duration {
+	var sum time.Duration
+	for _, day := range days {
+		sum += day.Worked
+	}
+	return sum
+}
+
+func expected(days []Day) time.Duration {
+	var sum time.Duration
+	for _, day := range days {
+		sum += day.Expected
+	}
+	return sum
+}
+
+// FormatHours formats a duration as signed hours and minutes, eg: -1:05
+func FormatHours(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	min := int64(d.Round(time.Minute) / time.Minute)
+	return fmt.Sprintf("%s%d:%02d", sign, min/60, min%60)
+}
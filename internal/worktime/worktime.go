@@ -0,0 +1,231 @@
+package worktime
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dateFormat = "2006-01-02"
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// DefaultHours is 8 hours from Monday to Friday.
+var DefaultHours = map[string]float64{"mon": 8, "tue": 8, "wed": 8, "thu": 8, "fri": 8}
+
+// Schedule is the working hours by weekday from a day on, eg: a part-time
+// schedule from {"from": "2026-09-01", "hours": {"mon": 4, "tue": 4}}. A
+// schedule without from applies since the beginning, it replaces
+// DefaultHours.
+type Schedule struct {
+	From  string             `json:"from"`
+	Hours map[string]float64 `json:"hours"`
+}
+
+type Config struct {
+	// The last schedule started before a day applies to it, DefaultHours
+	// before the first one
+	Schedules []Schedule `json:"schedules"`
+	// Holidays and working days, one per line: 2026-12-24 [hours] [# comment]
+	HolidayFile string `json:"holidayFile"`
+	// Start of the overtime balance, the first day of the month when empty
+	BalanceFrom string `json:"balanceFrom"`
+}
+
+type schedule struct {
+	from  time.Time
+	hours [7]time.Duration
+}
+
+// Calendar tells the expected working time of the days.
+type Calendar struct {
+	schedules   []schedule
+	holidays    map[string]time.Duration
+	balanceFrom time.Time
+}
+
+func New(config Config) (*Calendar, error) {
+	c := &Calendar{
+		holidays: map[string]time.Duration{},
+	}
+	def, err := newSchedule(time.Time{}, DefaultHours)
+	if err != nil {
+		return nil, err
+	}
+	c.schedules = append(c.schedules, def)
+	for _, s := range config.Schedules {
+		var from time.Time
+		if s.From != "" {
+			if from, err = time.ParseInLocation(dateFormat, s.From, time.Local); err != nil {
+				return nil, fmt.Errorf("invalid schedule start: %s", err)
+			}
+		}
+		parsed, err := newSchedule(from, s.Hours)
+		if err != nil {
+			return nil, err
+		}
+		c.schedules = append(c.schedules, parsed)
+	}
+	sort.SliceStable(c.schedules, func(i, j int) bool {
+		return c.schedules[i].from.Before(c.schedules[j].from)
+	})
+	if config.HolidayFile != "" {
+		if err := c.loadHolidays(config.HolidayFile); err != nil {
+			return nil, err
+		}
+	}
+	if config.BalanceFrom != "" {
+		if c.balanceFrom, err = time.ParseInLocation(dateFormat, config.BalanceFrom, time.Local); err != nil {
+			return nil, fmt.Errorf("invalid balance start: %s", err)
+		}
+	}
+	return c, nil
+}
+
+func newSchedule(from time.Time, hours map[string]float64) (schedule, error) {
+	s := schedule{from: from}
+	for name, h := range hours {
+		day, ok := weekdays[strings.ToLower(name)]
+		if !ok {
+			return s, fmt.Errorf("invalid weekday: `%s`", name)
+		}
+		s.hours[day] = time.Duration(h * float64(time.Hour))
+	}
+	return s, nil
+}
+
+func (c *Calendar) loadHolidays(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		day, err := time.ParseInLocation(dateFormat, fields[0], time.Local)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %s", path, line, err)
+		}
+		var hours float64
+		if len(fields) > 1 {
+			if hours, err = strconv.ParseFloat(fields[1], 64); err != nil {
+				return fmt.Errorf("%s:%d: invalid hours: %s", path, line, err)
+			}
+		}
+		c.holidays[day.Format(dateFormat)] = time.Duration(hours * float64(time.Hour))
+	}
+	return scanner.Err()
+}
+
+// Expected returns the working time of the day.
+func (c *Calendar) Expected(day time.Time) time.Duration {
+	if d, ok := c.holidays[day.Format(dateFormat)]; ok {
+		return d
+	}
+	s := c.schedules[0]
+	for _, next := range c.schedules[1:] {
+		if next.from.After(day) {
+			break
+		}
+		s = next
+	}
+	return s.hours[day.Weekday()]
+}
+
+// BalanceFrom returns the first day of the overtime balance.
+func (c *Calendar) BalanceFrom(now time.Time) time.Time {
+	if !c.balanceFrom.IsZero() {
+		return c.balanceFrom
+	}
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+}
+
+// Day is the worked and the expected time of a day.
+type Day struct {
+	Date     time.Time
+	Worked   time.Duration
+	Expected time.Duration
+}
+
+func (d Day) Balance() time.Duration {
+	return d.Worked - d.Expected
+}
+
+// Days returns the days between from and to, both included, worked is the
+// worked time by date (2006-01-02).
+func (c *Calendar) Days(from time.Time, to time.Time, worked map[string]time.Duration) []Day {
+	var days []Day
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		days = append(days, Day{
+			Date:     date,
+			Worked:   worked[date.Format(dateFormat)],
+			Expected: c.Expected(date),
+		})
+	}
+	return days
+}
+
+func Balance(days []Day) time.Duration {
+	var balance time.Duration
+	for _, day := range days {
+		balance += day.Balance()
+	}
+	return balance
+}
+
+// BalanceUntil is the balance of the days from from until yesterday and the
+// time worked today, the expected time of today is counted from tomorrow.
+func (c *Calendar) BalanceUntil(from time.Time, now time.Time, worked map[string]time.Duration) time.Duration {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	balance := Balance(c.Days(from, today.Add(-time.Nanosecond), worked))
+	if !from.After(now) {
+		balance += worked[today.Format(dateFormat)]
+	}
+	return balance
+}
+
+// Periods are the names of the report periods, see Period.
+var Periods = []string{"month", "week", "last-week", "this-month", "last-month"}
+
+// Period returns the first and the last moment of a report period: the days
+// of the previous month until now (month), the current or the previous week
+// and calendar month.
+func Period(name string, now time.Time) (time.Time, time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	firstDay := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	switch name {
+	case "", "month":
+		return today.AddDate(0, -1, 0), now, nil
+	case "week":
+		return monday, now, nil
+	case "last-week":
+		return monday.AddDate(0, 0, -7), monday.Add(-time.Nanosecond), nil
+	case "this-month":
+		return firstDay, now, nil
+	case "last-month":
+		return firstDay.AddDate(0, -1, 0), firstDay.Add(-time.Nanosecond), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("unknown period: `%s`", name)
+}
@@ -0,0 +1,206 @@
+package worktime
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+func TestExpected(t *testing.T) {
+	holidays := filepath.Join(t.TempDir(), "holidays")
+	err := os.WriteFile(holidays, []byte(`# 2026
+2026-03-03        # holiday
+2026-03-07 6      # working Saturday
+2026-09-08 2.5
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(Config{
+		Schedules: []Schedule{
+			{From: "2026-09-01", Hours: map[string]float64{"mon": 4, "Tue": 4, "wed": 4}},
+		},
+		HolidayFile: holidays,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		day      time.Time
+		expected time.Duration
+	}{
+		{date(2026, 3, 2), 8 * time.Hour},                     // Monday, default hours
+		{date(2026, 3, 3), 0},                                 // holiday
+		{date(2026, 3, 7), 6 * time.Hour},                     // working Saturday
+		{date(2026, 3, 8), 0},                                 // Sunday
+		{date(2026, 8, 31), 8 * time.Hour},                    // before the part-time schedule
+		{date(2026, 9, 1), 4 * time.Hour},                     // first day of it
+		{date(2026, 9, 3), 0},                                 // Thursday off
+		{date(2026, 9, 8), 150 * time.Minute},                 // holiday hours win
+		{date(2026, 9, 1).Add(15 * time.Hour), 4 * time.Hour}, // time of the day
+	}
+	for _, test := range tests {
+		if got := c.Expected(test.day); got != test.expected {
+			t.Errorf("%s: %s, expected %s", test.day.Format(time.RFC3339), got, test.expected)
+		}
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	always := Schedule{Hours: map[string]float64{"mon": 6, "fri": 6}}
+	partTime := Schedule{From: "2026-09-01", Hours: map[string]float64{"mon": 4}}
+	tests := []struct {
+		name      string
+		schedules []Schedule
+		day       time.Time
+		expected  time.Duration
+	}{
+		{"default hours", nil, date(2026, 3, 2), 8 * time.Hour},
+		{"without from", []Schedule{always}, date(2020, 1, 6), 6 * time.Hour},
+		{"without from, day off", []Schedule{always}, date(2026, 3, 3), 0},
+		{"before a later schedule", []Schedule{partTime, always}, date(2026, 8, 31), 6 * time.Hour},
+		{"after a later schedule", []Schedule{partTime, always}, date(2026, 9, 7), 4 * time.Hour},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := New(Config{Schedules: test.schedules})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.Expected(test.day); got != test.expected {
+				t.Errorf("%s: %s, expected %s", test.day.Format(dateFormat), got, test.expected)
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	holidays := filepath.Join(t.TempDir(), "holidays")
+	if err := os.WriteFile(holidays, []byte("2026-03-03\n2026-13-01\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		config Config
+		err    string
+	}{
+		{Config{Schedules: []Schedule{{From: "2026-09-01", Hours: map[string]float64{"monday": 4}}}}, "invalid weekday: `monday`"},
+		{Config{Schedules: []Schedule{{From: "09/01/2026"}}}, "invalid schedule start"},
+		{Config{BalanceFrom: "tomorrow"}, "invalid balance start"},
+		{Config{HolidayFile: holidays}, holidays + ":2: "},
+	}
+	for _, test := range tests {
+		if _, err := New(test.config); err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("%+v: error %v, expected %s", test.config, err, test.err)
+		}
+	}
+}
+
+func TestBalanceUntil(t *testing.T) {
+	c, err := New(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	worked := map[string]time.Duration{
+		"2026-03-02": 9 * time.Hour,
+		"2026-03-03": 7 * time.Hour,
+		"2026-03-04": 2 * time.Hour,
+	}
+	now := date(2026, 3, 4).Add(10 * time.Hour)
+	tests := []struct {
+		from    time.Time
+		balance time.Duration
+	}{
+		{date(2026, 3, 2), 2 * time.Hour}, // +1h -1h and today's 2h
+		{date(2026, 3, 4), 2 * time.Hour}, // today only
+		{date(2026, 3, 1), 2 * time.Hour}, // Sunday is not expected
+		{date(2026, 3, 5), 0},             // starts tomorrow
+	}
+	for _, test := range tests {
+		if got := c.BalanceUntil(test.from, now, worked); got != test.balance {
+			t.Errorf("from %s: %s, expected %s", test.from.Format(dateFormat), got, test.balance)
+		}
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	wednesday := date(2026, 3, 4).Add(15*time.Hour + 30*time.Minute)
+	sunday := date(2026, 3, 8).Add(20 * time.Hour)
+	lastNano := -time.Nanosecond
+	tests := []struct {
+		name     string
+		now      time.Time
+		from, to time.Time
+	}{
+		{"", wednesday, date(2026, 2, 4), wednesday},
+		{"month", wednesday, date(2026, 2, 4), wednesday},
+		{"week", wednesday, date(2026, 3, 2), wednesday},
+		{"week", sunday, date(2026, 3, 2), sunday},
+		{"week", date(2026, 3, 2), date(2026, 3, 2), date(2026, 3, 2)},
+		{"last-week", wednesday, date(2026, 2, 23), date(2026, 3, 2).Add(lastNano)},
+		{"last-week", sunday, date(2026, 2, 23), date(2026, 3, 2).Add(lastNano)},
+		{"this-month", wednesday, date(2026, 3, 1), wednesday},
+		{"last-month", wednesday, date(2026, 2, 1), date(2026, 3, 1).Add(lastNano)},
+		{"last-month", date(2026, 1, 15), date(2025, 12, 1), date(2026, 1, 1).Add(lastNano)},
+	}
+	for _, test := range tests {
+		from, to, err := Period(test.name, test.now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !from.Equal(test.from) || !to.Equal(test.to) {
+			t.Errorf("%q at %s: %s - %s, expected %s - %s", test.name, test.now,
+				from, to, test.from, test.to)
+		}
+	}
+	if _, _, err := Period("year", wednesday); err == nil || err.Error() != "unknown period: `year`" {
+		t.Errorf("unknown period: %v", err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	days := []Day{
+		{Date: date(2026, 3, 2), Worked: 8*time.Hour + 30*time.Minute, Expected: 8 * time.Hour},
+		{Date: date(2026, 3, 3), Worked: 7 * time.Hour, Expected: 8 * time.Hour},
+		{Date: date(2026, 3, 7)},
+	}
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"text", "2026-03-02 30\n2026-03-03 -60\n2026-03-07 0\n30\t-60\t "},
+		{"csv", "Date,Weekday,Worked,Expected,Balance\n" +
+			"2026-03-02,Mon,8:30,8:00,0:30\n" +
+			"2026-03-03,Tue,7:00,8:00,-1:00\n" +
+			"2026-03-07,Sat,0:00,0:00,0:00\n" +
+			"Total,,15:30,16:00,-0:30\n"},
+		{"tsv", "Date\tWeekday\tWorked\tExpected\tBalance\n" +
+			"2026-03-02\tMon\t8:30\t8:00\t0:30\n" +
+			"2026-03-03\tTue\t7:00\t8:00\t-1:00\n" +
+			"2026-03-07\tSat\t0:00\t0:00\t0:00\n" +
+			"Total\t\t15:30\t16:00\t-0:30\n"},
+		{"markdown", "| Date | Weekday | Worked | Expected | Balance |\n" +
+			"|------|---------|-------:|---------:|--------:|\n" +
+			"| 2026-03-02 | Mon | 8:30 | 8:00 | 0:30 |\n" +
+			"| 2026-03-03 | Tue | 7:00 | 8:00 | -1:00 |\n" +
+			"| 2026-03-07 | Sat | 0:00 | 0:00 | 0:00 |\n" +
+			"| **Total** | | **15:30** | **16:00** | **-0:30** |\n"},
+	}
+	for _, test := range tests {
+		var out strings.Builder
+		if err := Write(&out, test.format, days); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != test.expected {
+			t.Errorf("%s:\n%s\nexpected:\n%s", test.format, out.String(), test.expected)
+		}
+	}
+	if err := Write(&strings.Builder{}, "html", days); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
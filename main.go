@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/Ak-Army/i3barfeeder/gobar"
@@ -13,15 +14,33 @@ import (
 	"github.com/Ak-Army/xlog"
 )
 
+// Real-time signals as seen by the libc based tools, eg: pkill -RTMIN+3
+const (
+	sigRTMin = 34
+	sigRTMax = 64
+)
+
 func main() {
-	var logPath, configPath string
+	var logPath, configPath, socketPath, action string
 	flag.StringVar(&logPath, "log", "/dev/null", "Log path. Default: /dev/null")
 	flag.StringVar(&logPath, "l", "/dev/null", "Log file to use. Default: /dev/null")
 	flag.StringVar(&configPath, "config", "", "Config path.")
 	flag.StringVar(&configPath, "c", "", "Config path (in JSON).")
+	flag.StringVar(&socketPath, "socket", gobar.DefaultSocket(), "IPC socket path, empty disables it.")
+	flag.StringVar(&action, "action", "", "Run an action in the running bar, eg: toggl.toggle [instance], list them with: list, or show the sent notifications with: notifications")
 
 	flag.Parse()
 
+	if action != "" {
+		answer, err := gobar.SendAction(socketPath, strings.Join(append([]string{action}, flag.Args()...), " "))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to run action: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(answer)
+		return
+	}
+
 	logfile, err := os.OpenFile(logPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Unable to open log file: %q", err)
@@ -44,22 +63,39 @@ func main() {
 	if err != nil {
 		log.Fatal("Unable to load config", err)
 	}
-	bar.Start()
+	if socketPath != "" {
+		ipc, err := bar.ListenIPC(socketPath)
+		if err != nil {
+			log.Warnf("Unable to listen on IPC socket: %s", err)
+		} else {
+			defer ipc.Close()
+		}
+	}
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGCONT)
+	for sig := sigRTMin; sig <= sigRTMax; sig++ {
+		signal.Notify(sigs, syscall.Signal(sig))
+	}
+	go bar.Start()
+loop:
 	for {
 		sig := <-sigs
 		log.Debugf("Received signal: %q", sig)
+		if s, ok := sig.(syscall.Signal); ok && int(s) >= sigRTMin && int(s) <= sigRTMax {
+			bar.RefreshSignal(int(s) - sigRTMin)
+			continue
+		}
 		switch sig {
 		/*case syscall.SIGTERM:
 			bar.Stop()
 		case syscall.SIGCONT:
 			bar.Stop()
 			bar.ReStart()*/
-		case syscall.SIGINT:
-			return
+		case syscall.SIGINT, syscall.SIGTERM:
+			break loop
 		}
 	}
+	bar.SaveState()
+	bar.Close()
 	log.Info("End")
-	os.Exit(0)
 }
@@ -3,8 +3,10 @@ package modules
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/Ak-Army/i3barfeeder/gobar"
+	"github.com/Ak-Army/i3barfeeder/internal/notify"
 
 	"github.com/Ak-Army/xlog"
 )
@@ -14,6 +16,7 @@ func init() {
 		return &Battery{
 			InterfaceName: "BAT0",
 			barConfig:     defaultBarConfig(),
+			sysFS:         defaultSysFS(),
 		}
 	})
 }
@@ -21,9 +24,13 @@ func init() {
 type Battery struct {
 	gobar.ModuleInterface
 	InterfaceName string `json:"interfaceName"`
-	barConfig     barConfig
-	log           xlog.Logger
-	fullEnergy    float64
+	// Send a notification when discharging below this percent
+	NotifyBelow int `json:"notifyBelow"`
+	barConfig   barConfig
+	sysFS       sysFS
+	log         xlog.Logger
+	fullEnergy  float64
+	metricValues
 }
 
 func (m *Battery) InitModule(config json.RawMessage, log xlog.Logger) error {
@@ -35,31 +42,71 @@ func (m *Battery) InitModule(config json.RawMessage, log xlog.Logger) error {
 		if err := json.Unmarshal(config, &m.barConfig); err != nil {
 			return err
 		}
+		if err := json.Unmarshal(config, &m.sysFS); err != nil {
+			return err
+		}
+	}
+	var err error
+	m.fullEnergy, err = m.readEnergy("energy_full")
+	if err != nil {
+		m.log.Warnf("Unable to read battery energy: %s", err)
 	}
-	m.fullEnergy = m.readEnergy("energy_full")
 
 	return nil
 }
 
 func (m *Battery) UpdateInfo(info gobar.BlockInfo) gobar.BlockInfo {
-	currEnergy := m.readEnergy("energy_now")
+	if m.fullEnergy == 0 {
+		var err error
+		if m.fullEnergy, err = m.readEnergy("energy_full"); err != nil {
+			setError(&info, err)
+			return info
+		}
+	}
+	currEnergy, err := m.readEnergy("energy_now")
+	if err != nil {
+		setError(&info, err)
+		return info
+	}
 	freePercent := 100 * (currEnergy / m.fullEnergy)
+	m.set("battery_percent", freePercent)
 
 	info.ShortText = fmt.Sprintf("%d %s", int(freePercent), "%")
-	info.FullText = makeBar(freePercent, m.barConfig)
+	setBar(&info, freePercent, m.barConfig)
+	if m.NotifyBelow > 0 && freePercent < float64(m.NotifyBelow) && m.readStatus() == "Discharging" {
+		gobar.Notify(notify.Notification{
+			Key:     "battery:" + m.InterfaceName,
+			Summary: "Battery low",
+			Body:    fmt.Sprintf("%s is at %d%%", m.InterfaceName, int(freePercent)),
+			Icon:    "battery-caution",
+			Urgency: notify.UrgencyCritical,
+		})
+	}
 	return info
 }
 
-func (m *Battery) readEnergy(name string) float64 {
+func (m *Battery) readStatus() string {
+	var status string
+	err := m.sysFS.readLines("sys/class/power_supply/"+m.InterfaceName+"/status", func(line string) bool {
+		status = strings.TrimSpace(line)
+		return false
+	})
+	if err != nil {
+		m.log.Warnf("Unable to read battery status: %s", err)
+	}
+	return status
+}
+
+func (m *Battery) readEnergy(name string) (float64, error) {
 	var energy float64
 	callback := func(line string) bool {
 		fmt.Sscanf(line, "%f", &energy)
 		return true
 	}
-	readLines("/sys/class/power_supply/"+m.InterfaceName+"/"+name, callback)
-	return energy
+	err := m.sysFS.readLines("sys/class/power_supply/"+m.InterfaceName+"/"+name, callback)
+	return energy, err
 }
 
-func (m Battery) HandleClick(cm gobar.ClickMessage, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
+func (m *Battery) HandleClick(cm gobar.ClickMessage, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
 	return nil, nil
 }
@@ -0,0 +1,324 @@
+package modules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/gobar"
+)
+
+func init() {
+	gobar.AddModule("Carousel", func() gobar.ModuleInterface {
+		return &Carousel{
+			Rotate: 5,
+		}
+	})
+}
+
+type Carousel struct {
+	sync.Mutex
+	gobar.ModuleInterface
+	// Seconds between two automatic switches, 0 disables the rotation
+	Rotate     int             `json:"rotate"`
+	Modules    []*carouselItem `json:"modules"`
+	current    int
+	lastSwitch time.Time
+	log        xlog.Logger
+	done       chan struct{}
+	rotating   sync.WaitGroup
+	refreshMu  sync.Mutex
+	refresh    func()
+}
+
+type carouselItem struct {
+	ModuleName string          `json:"module"`
+	Label      string          `json:"label"`
+	Info       gobar.BlockInfo `json:"info"`
+	Config     json.RawMessage `json:"config"`
+	module     gobar.ModuleInterface
+}
+
+func (m *Carousel) InitModule(config json.RawMessage, log xlog.Logger) error {
+	m.log = log
+	if config != nil {
+		if err := json.Unmarshal(config, m); err != nil {
+			return err
+		}
+	}
+	if len(m.Modules) == 0 {
+		return errors.New("carousel without modules")
+	}
+	for i, item := range m.Modules {
+		if item.ModuleName == "Carousel" {
+			return fmt.Errorf("carousel item %d: nested carousel is not supported", i)
+		}
+		module, err := gobar.NewModule(item.ModuleName, item.Config, m.childLog(i))
+		if err != nil {
+			return fmt.Errorf("carousel item %d: %s", i, err)
+		}
+		if refresher, ok := module.(gobar.Refresher); ok {
+			refresher.SetRefresh(m.refreshBlock)
+		}
+		item.module = module
+	}
+	m.lastSwitch = gobar.Now()
+	if m.Rotate > 0 {
+		m.done = make(chan struct{})
+		m.rotating.Add(1)
+		go m.rotate(m.done)
+	}
+	return nil
+}
+
+// childLog gives every module its own block instance, eg: two time trackers
+// must not share their queue file.
+func (m *Carousel) childLog(i int) xlog.Logger {
+	log := xlog.Copy(m.log)
+	log.SetField("block", fmt.Sprintf("%s.%d", gobar.BlockInstance(m.log), i))
+	return log
+}
+
+func (m *Carousel) SetRefresh(refresh func()) {
+	m.refreshMu.Lock()
+	defer m.refreshMu.Unlock()
+	m.refresh = refresh
+}
+
+func (m *Carousel) refreshBlock() {
+	m.refreshMu.Lock()
+	refresh := m.refresh
+	m.refreshMu.Unlock()
+	if refresh != nil {
+		refresh()
+	}
+}
+
+// rotate switches to the next module every Rotate seconds, a manual switch
+// restarts the period.
+func (m *Carousel) rotate(done chan struct{}) {
+	defer m.rotating.Done()
+	period := time.Duration(m.Rotate) * time.Second
+	wait := period
+	for {
+		select {
+		case <-done:
+			return
+		case <-gobar.After(wait):
+		}
+		m.Lock()
+		wait = period - gobar.Now().Sub(m.lastSwitch)
+		switched := wait <= 0
+		if switched {
+			m.step(1)
+			wait = period
+		}
+		m.Unlock()
+		if switched {
+			m.refreshBlock()
+		}
+	}
+}
+
+// Close stops the rotation and closes the children.
+func (m *Carousel) Close() error {
+	m.Lock()
+	if m.done != nil {
+		close(m.done)
+		m.done = nil
+	}
+	m.Unlock()
+	m.rotating.Wait()
+	var lastErr error
+	for _, item := range m.Modules {
+		if closer, ok := item.module.(gobar.Closer); ok {
+			if err := closer.Close(); err != nil {
+				lastErr = err
+			}
+		}
+	}
+	return lastErr
+}
+
+func (m *Carousel) UpdateInfo(info gobar.BlockInfo) gobar.BlockInfo {
+	m.Lock()
+	defer m.Unlock()
+	item := m.Modules[m.current]
+	item.Info = item.module.UpdateInfo(m.childInfo(item, info))
+	return m.render(item, info)
+}
+
+type carouselState struct {
+	Current int `json:"current"`
+	// States of the modules by index
+	Modules map[int]json.RawMessage `json:"modules"`
+}
+
+func (m *Carousel) SaveState() (json.RawMessage, error) {
+	m.Lock()
+	defer m.Unlock()
+	state := carouselState{Current: m.current, Modules: map[int]json.RawMessage{}}
+	for i, item := range m.Modules {
+		saver, ok := item.module.(gobar.StateSaver)
+		if !ok {
+			continue
+		}
+		data, err := saver.SaveState()
+		if err != nil {
+			return nil, fmt.Errorf("carousel item %d: %s", i, err)
+		}
+		state.Modules[i] = data
+	}
+	return json.Marshal(state)
+}
+
+func (m *Carousel) RestoreState(data json.RawMessage) error {
+	var state carouselState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	m.Lock()
+	defer m.Unlock()
+	if state.Current >= 0 && state.Current < len(m.Modules) {
+		m.current = state.Current
+	}
+	var lastErr error
+	for i, item := range m.Modules {
+		saver, ok := item.module.(gobar.StateSaver)
+		if !ok || state.Modules[i] == nil {
+			continue
+		}
+		if err := saver.RestoreState(state.Modules[i]); err != nil {
+			lastErr = fmt.Errorf("carousel item %d: %s", i, err)
+		}
+	}
+	return lastErr
+}
+
+// Metrics returns the metrics of the modules.
+func (m *Carousel) Metrics() map[string]float64 {
+	metrics := map[string]float64{}
+	for _, item := range m.Modules {
+		if provider, ok := item.module.(gobar.MetricsProvider); ok {
+			for name, value := range provider.Metrics() {
+				metrics[name] = value
+			}
+		}
+	}
+	return metrics
+}
+
+// {"name":"Carousel","instance":"id_0","button":5,"x":2991,"y":12}
+func (m *Carousel) HandleClick(cm gobar.ClickMessage, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
+	m.Lock()
+	defer m.Unlock()
+	switch cm.Button {
+	case 4: // scroll up, previous module
+		m.step(-1)
+	case 5: // scroll down, next module
+		m.step(1)
+	default:
+		item := m.Modules[m.current]
+		newInfo, err := item.module.HandleClick(cm, m.childInfo(item, info))
+		if newInfo == nil {
+			return nil, err
+		}
+		item.Info = *newInfo
+		info = m.render(item, info)
+		return &info, err
+	}
+	item := m.Modules[m.current]
+	item.Info = item.module.UpdateInfo(m.childInfo(item, info))
+	info = m.render(item, info)
+	return &info, nil
+}
+
+// Actions returns the carousel's own actions and the actions of its modules.
+func (m *Carousel) Actions() []string {
+	actions := []string{"carousel.next", "carousel.prev"}
+	seen := map[string]bool{}
+	for _, item := range m.Modules {
+		if handler, ok := item.module.(gobar.ActionHandler); ok {
+			for _, action := range handler.Actions() {
+				if !seen[action] {
+					seen[action] = true
+					actions = append(actions, action)
+				}
+			}
+		}
+	}
+	return actions
+}
+
+func (m *Carousel) HandleAction(name string, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
+	switch name {
+	case "carousel.next":
+		return m.HandleClick(gobar.ClickMessage{Button: 5}, info)
+	case "carousel.prev":
+		return m.HandleClick(gobar.ClickMessage{Button: 4}, info)
+	}
+	m.Lock()
+	defer m.Unlock()
+	for i, item := range m.Modules {
+		handler, ok := item.module.(gobar.ActionHandler)
+		if !ok || !hasString(handler.Actions(), name) {
+			continue
+		}
+		newInfo, err := handler.HandleAction(name, m.childInfo(item, info))
+		if newInfo != nil {
+			item.Info = *newInfo
+		}
+		if newInfo == nil || i != m.current {
+			return nil, err
+		}
+		info = m.render(item, info)
+		return &info, err
+	}
+	return nil, fmt.Errorf("action not found: `%s`", name)
+}
+
+func (m *Carousel) step(dir int) {
+	m.current = (m.current + dir + len(m.Modules)) % len(m.Modules)
+	m.lastSwitch = gobar.Now()
+}
+
+// childInfo builds the info passed to the child module: the child's last
+// state, with the carousel's own values as defaults.
+func (m *Carousel) childInfo(item *carouselItem, info gobar.BlockInfo) gobar.BlockInfo {
+	child := item.Info
+	if child.TextColor == "" {
+		child.TextColor = info.TextColor
+	}
+	if child.BackgroundColor == "" {
+		child.BackgroundColor = info.BackgroundColor
+	}
+	if child.BorderColor == "" {
+		child.BorderColor = info.BorderColor
+	}
+	if child.Markup == "" {
+		child.Markup = info.Markup
+	}
+	child.Name = info.Name
+	child.Instance = info.Instance
+	child.Failed = false
+	return child
+}
+
+func (m *Carousel) render(item *carouselItem, info gobar.BlockInfo) gobar.BlockInfo {
+	child := item.Info
+	child.Name = info.Name
+	child.Instance = info.Instance
+	child.BorderTop = info.BorderTop
+	child.BorderBottom = info.BorderBottom
+	child.BorderLeft = info.BorderLeft
+	child.BorderRight = info.BorderRight
+	if item.Label != "" {
+		child.FullText = item.Label + " " + child.FullText
+		child.ShortText = item.Label + " " + child.ShortText
+	}
+	return child
+}
@@ -0,0 +1,102 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/gobar"
+	"github.com/Ak-Army/i3barfeeder/gobartest"
+)
+
+func TestCarouselRotate(t *testing.T) {
+	clock := gobartest.NewClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	gobar.SetClock(clock)
+	defer gobar.SetClock(nil)
+
+	m := &Carousel{}
+	err := m.InitModule(json.RawMessage(`{"rotate":5,"modules":[
+		{"module":"StaticText","info":{"full_text":"first"}},
+		{"module":"StaticText","info":{"full_text":"second"}}]}`), xlog.GetLogger())
+	if err != nil {
+		t.Fatalf("InitModule: %s", err)
+	}
+	defer m.Close()
+	refreshed := make(chan struct{}, 1)
+	m.SetRefresh(func() { refreshed <- struct{}{} })
+	info := gobar.BlockInfo{Name: "Carousel", Instance: "test"}
+	waitTimer := func() {
+		for clock.Waiters() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	if got := m.UpdateInfo(info).FullText; got != "first" {
+		t.Fatalf("full text: %q, expected: first", got)
+	}
+	waitTimer()
+	clock.Advance(5 * time.Second)
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("the block was not refreshed")
+	}
+	if got := m.UpdateInfo(info).FullText; got != "second" {
+		t.Errorf("full text: %q, expected: second", got)
+	}
+
+	// a manual switch restarts the period
+	waitTimer()
+	clock.Advance(3 * time.Second)
+	m.HandleClick(gobar.ClickMessage{Button: 5}, info)
+	waitTimer()
+	clock.Advance(2 * time.Second)
+	waitTimer()
+	select {
+	case <-refreshed:
+		t.Error("rotated 2 seconds after a manual switch")
+	default:
+	}
+	if got := m.UpdateInfo(info).FullText; got != "first" {
+		t.Errorf("full text: %q, expected: first", got)
+	}
+}
+
+func TestCarouselModules(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", dir)
+	log := xlog.Copy(xlog.GetLogger())
+	log.SetField("block", "work")
+	tracker := func(name string) string {
+		return fmt.Sprintf(`{"module":"TimeTracker","config":{"backend":"local","file":%q}}`, filepath.Join(dir, name))
+	}
+	m := &Carousel{}
+	err := m.InitModule(json.RawMessage(`{"rotate":0,"modules":[`+tracker("a.jsonl")+`,`+tracker("b.jsonl")+`]}`), log)
+	if err != nil {
+		t.Fatalf("InitModule: %s", err)
+	}
+	data, err := m.SaveState()
+	if err != nil {
+		t.Fatalf("SaveState: %s", err)
+	}
+	var state carouselState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Modules) != 2 {
+		t.Errorf("saved the state of %d modules, expected: 2", len(state.Modules))
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+	for _, name := range []string{"local-work.0-queue.json", "local-work.1-queue.json"} {
+		if _, err := os.Stat(filepath.Join(dir, "i3barfeeder", name)); err != nil {
+			t.Errorf("queue file of the module: %s", err)
+		}
+	}
+}
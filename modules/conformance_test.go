@@ -0,0 +1,34 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/Ak-Army/i3barfeeder/gobartest"
+)
+
+func TestConformance(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", dir)
+	// the API backends run on the local one, the test does not depend on
+	// the network
+	for _, backend := range []string{"toggl", "clockify"} {
+		saved := trackerBackends[backend]
+		trackerBackends[backend] = trackerBackends["local"]
+		t.Cleanup(func() { trackerBackends[backend] = saved })
+	}
+	tracker := func(backend string) json.RawMessage {
+		return json.RawMessage(fmt.Sprintf(`{"backend":%q,"file":%q,"queueFile":%q,"picker":["false"]}`,
+			backend, filepath.Join(dir, backend+".jsonl"), filepath.Join(dir, backend+"-queue.json")))
+	}
+	gobartest.ConformanceAll(t, map[string]json.RawMessage{
+		"Carousel":    json.RawMessage(`{"modules":[{"module":"StaticText","config":{"text":"conformance"}},{"module":"DateTime"}]}`),
+		"ExternalCmd": json.RawMessage(`{"exec":"echo conformance"}`),
+		"GCal":        json.RawMessage(`{"secretFile":"testdata/gcal/credentials.json","tokenFile":"testdata/gcal/token.json"}`),
+		"TimeTracker": tracker("local"),
+		"Toggl":       tracker("toggl"),
+		"Clockify":    tracker("clockify"),
+	})
+}
@@ -3,7 +3,6 @@ package modules
 import (
 	"encoding/json"
 	"fmt"
-	"os/exec"
 	"strconv"
 	"strings"
 
@@ -15,42 +14,66 @@ import (
 func init() {
 	gobar.AddModule("CpuInfo", func() gobar.ModuleInterface {
 		return &CpuInfo{
-			barConfig: defaultBarConfig(),
+			barConfig:     defaultBarConfig(),
+			historyConfig: defaultHistoryConfig(),
+			sysFS:         defaultSysFS(),
 		}
 	})
 }
 
 type CpuInfo struct {
 	gobar.ModuleInterface
-	barConfig barConfig
+	barConfig     barConfig
+	historyConfig historyConfig
+	history       history
+	sysFS         sysFS
+	prevTotal     uint64
+	prevIdle      uint64
+	metricValues
 }
 
-var prevTotal, prevIdle uint64
-
 func (m *CpuInfo) InitModule(config json.RawMessage, log xlog.Logger) error {
 	if config != nil {
-		return json.Unmarshal(config, &m.barConfig)
+		if err := json.Unmarshal(config, &m.barConfig); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(config, &m.sysFS); err != nil {
+			return err
+		}
+		return json.Unmarshal(config, &m.historyConfig)
 	}
 	return nil
 }
 
-func (m CpuInfo) UpdateInfo(info gobar.BlockInfo) gobar.BlockInfo {
-	cpuUsage := m.CpuInfo()
+func (m *CpuInfo) UpdateInfo(info gobar.BlockInfo) gobar.BlockInfo {
+	cpuUsage, err := m.CpuInfo()
+	if err != nil {
+		setError(&info, err)
+		return info
+	}
+	m.set("cpu_usage_percent", cpuUsage)
 	info.ShortText = fmt.Sprintf("%d %s", int(cpuUsage), "%")
-	info.FullText = makeBar(cpuUsage, m.barConfig)
+	if m.historyConfig.enabled() {
+		m.history.add(cpuUsage, m.historyConfig.HistorySize)
+		info.FullText = makeGraph(&m.history, 100, m.historyConfig)
+		if m.historyConfig.needsMarkup() {
+			info.Markup = gobar.MarkupPango
+		}
+		return info
+	}
+	setBar(&info, cpuUsage, m.barConfig)
 	return info
 }
-func (m CpuInfo) HandleClick(cm gobar.ClickMessage, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
-	split := strings.Split("gnome-system-monitor -p", " ")
-	return nil, exec.Command(split[0], split[1:]...).Start()
+func (m *CpuInfo) HandleClick(cm gobar.ClickMessage, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
+	return clickError(info, gobar.Launch("gnome-system-monitor", "-p"))
 }
 
-func (m CpuInfo) CpuInfo() (cpuUsage float64) {
+func (m *CpuInfo) CpuInfo() (cpuUsage float64, err error) {
 	// Return the percent utilization of the CPU.
 	var idle, total uint64
 	callback := func(line string) bool {
 		fields := strings.Fields(line)
-		if fields[0] == "cpu" {
+		if len(fields) > 0 && fields[0] == "cpu" {
 			numFields := len(fields)
 			for i := 1; i < numFields; i++ {
 				val, _ := strconv.ParseUint(fields[i], 10, 64)
@@ -63,14 +86,16 @@ func (m CpuInfo) CpuInfo() (cpuUsage float64) {
 		}
 		return true
 	}
-	readLines("/proc/stat", callback)
+	if err = m.sysFS.readLines("proc/stat", callback); err != nil {
+		return
+	}
 
-	if prevIdle > 0 {
-		idleTicks := float64(idle - prevIdle)
-		totalTicks := float64(total - prevTotal)
+	if m.prevIdle > 0 && total > m.prevTotal {
+		idleTicks := float64(idle - m.prevIdle)
+		totalTicks := float64(total - m.prevTotal)
 		cpuUsage = 100 * (totalTicks - idleTicks) / totalTicks
 	}
-	prevIdle = idle
-	prevTotal = total
+	m.prevIdle = idle
+	m.prevTotal = total
 	return
 }
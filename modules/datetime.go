@@ -3,7 +3,6 @@ package modules
 import (
 	"encoding/json"
 	"fmt"
-	"os/exec"
 	"time"
 
 	"github.com/Ak-Army/xlog"
@@ -52,7 +51,7 @@ func (m *DateTime) InitModule(config json.RawMessage, log xlog.Logger) error {
 
 func (m DateTime) UpdateInfo(info gobar.BlockInfo) gobar.BlockInfo {
 	var now time.Time
-	now = time.Now()
+	now = gobar.Now()
 	if m.location != nil {
 		now = now.In(m.location)
 	}
@@ -62,5 +61,5 @@ func (m DateTime) UpdateInfo(info gobar.BlockInfo) gobar.BlockInfo {
 	return info
 }
 func (m DateTime) HandleClick(cm gobar.ClickMessage, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
-	return nil, exec.Command("gsimplecal").Run()
+	return clickError(info, gobar.Launch("gsimplecal"))
 }
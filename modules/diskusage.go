@@ -3,13 +3,12 @@ package modules
 import (
 	"encoding/json"
 	"fmt"
-	"os/exec"
-	"strings"
 	"syscall"
 
 	"github.com/Ak-Army/xlog"
 
 	"github.com/Ak-Army/i3barfeeder/gobar"
+	"github.com/Ak-Army/i3barfeeder/internal/notify"
 )
 
 func init() {
@@ -23,8 +22,11 @@ func init() {
 
 type DiskUsage struct {
 	gobar.ModuleInterface
-	Path      string
-	barConfig barConfig
+	Path string
+	// Send a notification when the disk usage is above this percent
+	NotifyAbove int `json:"notifyAbove"`
+	barConfig   barConfig
+	metricValues
 }
 
 func (m *DiskUsage) InitModule(config json.RawMessage, log xlog.Logger) error {
@@ -37,20 +39,31 @@ func (m *DiskUsage) InitModule(config json.RawMessage, log xlog.Logger) error {
 	return nil
 }
 
-func (m DiskUsage) UpdateInfo(info gobar.BlockInfo) gobar.BlockInfo {
+func (m *DiskUsage) UpdateInfo(info gobar.BlockInfo) gobar.BlockInfo {
 	free, total := m.diskUsage()
 	freePercent := 100 - (100 * (free / total))
+	m.set("disk_used_percent", freePercent)
+	m.set("disk_free_bytes", free)
+	m.set("disk_total_bytes", total)
 	info.ShortText = fmt.Sprintf("%d %s", int(freePercent), "%")
-	info.FullText = makeBar(freePercent, m.barConfig)
+	setBar(&info, freePercent, m.barConfig)
+	if m.NotifyAbove > 0 && freePercent > float64(m.NotifyAbove) {
+		gobar.Notify(notify.Notification{
+			Key:     "disk:" + m.Path,
+			Summary: "Disk nearly full",
+			Body:    fmt.Sprintf("%s is %d%% full", m.Path, int(freePercent)),
+			Icon:    "drive-harddisk",
+			Urgency: notify.UrgencyCritical,
+		})
+	}
 	return info
 }
 
-func (m DiskUsage) HandleClick(cm gobar.ClickMessage, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
-	split := strings.Split("gnome-system-monitor -f", " ")
-	return nil, exec.Command(split[0], split[1:]...).Start()
+func (m *DiskUsage) HandleClick(cm gobar.ClickMessage, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
+	return clickError(info, gobar.Launch("gnome-system-monitor", "-f"))
 }
 
-func (m DiskUsage) diskUsage() (free float64, total float64) {
+func (m *DiskUsage) diskUsage() (free float64, total float64) {
 	// Return bytes free and total bytes.
 	buf := new(syscall.Statfs_t)
 	err := syscall.Statfs(m.Path, buf)
@@ -1,10 +1,21 @@
 package modules
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
-	"github.com/Ak-Army/xlog"
+	"errors"
+	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Ak-Army/xlog"
 
 	"github.com/Ak-Army/i3barfeeder/gobar"
 )
@@ -16,6 +27,7 @@ func init() {
 }
 
 type ExternalCmd struct {
+	sync.Mutex
 	gobar.ModuleInterface
 	//Command to be executed (using "/bin/sh -c [command]")
 	Exec string `json:"exec"`
@@ -33,69 +45,499 @@ type ExternalCmd struct {
 	// "scroll-(up|down)" will be executed using "/bin/sh -c [command]"
 	ScrollUp   string `json:"scroll_up"`
 	ScrollDown string `json:"scroll_down"`
+
+	// Use the i3blocks protocol: the output lines are the full text, short
+	// text and color, exit code 33 marks the block urgent and clicks without
+	// own command run the exec command with BLOCK_BUTTON set.
+	I3Blocks bool `json:"i3blocks"`
+	// Value of BLOCK_INSTANCE, the i3bar instance is used when empty
+	Instance string `json:"instance"`
+
+	// Start the exec command once, every line it prints is a new block state
+	Persist bool `json:"persist"`
+	// Format of the command output: "text" or "json", a JSON object with the
+	// fields of gobar.BlockInfo is merged over the configured info
+	Format string `json:"format"`
+	// Write the click events as JSON lines to the persistent command's stdin
+	ClickStdin bool `json:"click_stdin"`
+
+	// Run exec without shell, the first item is the program
+	Argv []string `json:"argv"`
+	// Seconds after a command is killed, 0 means no timeout
+	Timeout int `json:"timeout"`
+	// Extra environment variables of the commands
+	Env map[string]string `json:"env"`
+	// Working directory of the commands
+	Cwd string `json:"cwd"`
+	// Maximum number of commands of the block running at the same time,
+	// updates over it keep the previous state, clicks wait for a free slot
+	MaxConcurrent int `json:"max_concurrent"`
+	// Text of failures, {error} and {stderr} are replaced
+	ErrorFormat string `json:"error_format"`
+	// Number of the last stderr lines in {stderr}
+	StderrLines int `json:"stderr_lines"`
+
+	log      xlog.Logger
+	refresh  func()
+	lastLine string
+	// click lines of the running persistent command, nil when it is not
+	// running
+	clicks  chan []byte
+	running chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	last    *gobar.BlockInfo
 }
 
+var errBusy = errors.New("too many running commands")
+
+// cmdError is a failed command with the end of its stderr.
+type cmdError struct {
+	err    error
+	stderr []string
+}
+
+func (e *cmdError) Error() string {
+	return e.err.Error()
+}
+
+func (e *cmdError) Unwrap() error {
+	return e.err
+}
+
+const (
+	persistMinBackoff = time.Second
+	persistMaxBackoff = time.Minute
+)
+
+// i3blocks exit code of urgent blocks
+const urgentExitCode = 33
+
+// clickWait is how long a click waits for a running command before it is
+// dropped.
+const clickWait = 10 * time.Second
+
 func (m *ExternalCmd) InitModule(config json.RawMessage, log xlog.Logger) error {
+	m.log = log
 	if config != nil {
 		if err := json.Unmarshal(config, m); err != nil {
 			return err
 		}
 	}
+	if m.MaxConcurrent <= 0 {
+		m.MaxConcurrent = 1
+	}
+	if m.ErrorFormat == "" {
+		m.ErrorFormat = "{error}"
+	}
+	if m.StderrLines <= 0 {
+		m.StderrLines = 1
+	}
+	m.running = make(chan struct{}, m.MaxConcurrent)
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	if m.Persist {
+		go m.runPersistent()
+	}
 	return nil
 }
 
+// Close kills the persistent command and stops restarting it.
+func (m *ExternalCmd) Close() error {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	return nil
+}
+
+func (m *ExternalCmd) SetRefresh(refresh func()) {
+	m.Lock()
+	defer m.Unlock()
+	m.refresh = refresh
+}
+
 func (m *ExternalCmd) UpdateInfo(info gobar.BlockInfo) gobar.BlockInfo {
+	if m.Persist {
+		m.Lock()
+		defer m.Unlock()
+		m.applyLine(m.lastLine, &info)
+		return info
+	}
 	if m.ExecIf != "" {
-		_, err := exec.Command("sh", "-c", m.ExecIf).Output()
+		_, err := m.run(shellArgv(m.ExecIf), nil, 0)
 		if err != nil {
 			return info
 		}
 	}
-	m.execCommand(m.Exec, &info)
+	m.execCommand(m.execArgv(), m.blockEnv(info, nil), &info, 0)
 
 	return info
 }
 
 func (m *ExternalCmd) HandleClick(cm gobar.ClickMessage, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
+	var cmd string
 	switch cm.Button {
 	case 1: // left button
-		if m.ClickLeft != "" {
-			m.execCommand(m.ClickLeft, &info)
-			return &info, nil
-		}
+		cmd = m.ClickLeft
 	case 2: // middle button
-		if m.ClickMiddle != "" {
-			m.execCommand(m.ClickMiddle, &info)
-			return &info, nil
+		cmd = m.ClickMiddle
+	case 3: // right click
+		cmd = m.ClickRight
+	case 4: // scroll up
+		cmd = m.ScrollUp
+	case 5: // scroll down
+		cmd = m.ScrollDown
+	}
+	if m.Persist && m.ClickStdin {
+		return nil, m.writeClick(cm)
+	}
+	argv := shellArgv(cmd)
+	if cmd == "" && m.I3Blocks && !m.Persist {
+		argv = m.execArgv()
+	} else if cmd == "" {
+		return nil, nil
+	}
+	if err := m.execCommand(argv, m.blockEnv(info, &cm), &info, clickWait); err != nil {
+		m.log.Warnf("Click %d dropped: %s", cm.Button, err)
+		return nil, err
+	}
+	return &info, nil
+}
+
+func shellArgv(cmd string) []string {
+	return []string{"sh", "-c", cmd}
+}
+
+func (m *ExternalCmd) execArgv() []string {
+	if len(m.Argv) > 0 {
+		return m.Argv
+	}
+	return shellArgv(m.Exec)
+}
+
+func (m *ExternalCmd) command(ctx context.Context, argv []string, env []string) *exec.Cmd {
+	c := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	c.Dir = m.Cwd
+	c.Env = os.Environ()
+	for k, v := range m.Env {
+		c.Env = append(c.Env, k+"="+v)
+	}
+	c.Env = append(c.Env, env...)
+	// kill the whole process group, so the children of the shell can not
+	// keep the output open after a timeout
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	c.Cancel = func() error {
+		return syscall.Kill(-c.Process.Pid, syscall.SIGKILL)
+	}
+	c.WaitDelay = time.Second
+	return c
+}
+
+// acquire takes a slot of the running commands, it waits at most wait for
+// one.
+func (m *ExternalCmd) acquire(wait time.Duration) bool {
+	select {
+	case m.running <- struct{}{}:
+		return true
+	default:
+	}
+	if wait <= 0 {
+		return false
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case m.running <- struct{}{}:
+		return true
+	case <-t.C:
+		return false
+	}
+}
+
+// run executes the command with the configured limits, the stderr is logged
+// and kept in the error.
+func (m *ExternalCmd) run(argv []string, env []string, wait time.Duration) ([]byte, error) {
+	if !m.acquire(wait) {
+		return nil, errBusy
+	}
+	defer func() { <-m.running }()
+	ctx := context.Background()
+	if m.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(m.Timeout)*time.Second)
+		defer cancel()
+	}
+	c := m.command(ctx, argv, env)
+	var stderr bytes.Buffer
+	c.Stderr = &stderr
+	out, err := c.Output()
+	lines := strings.Split(strings.TrimRight(stderr.String(), "\n"), "\n")
+	if stderr.Len() > 0 {
+		m.log.Warnf("Command %q stderr: %s", strings.Join(argv, " "), strings.Join(lines, " | "))
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timeout after %ds", m.Timeout)
+	}
+	if err == nil || stderr.Len() == 0 {
+		return out, err
+	}
+	if len(lines) > m.StderrLines {
+		lines = lines[len(lines)-m.StderrLines:]
+	}
+	return out, &cmdError{err: err, stderr: lines}
+}
+
+func (m *ExternalCmd) formatError(err error) string {
+	var stderr []string
+	var cmdErr *cmdError
+	if errors.As(err, &cmdErr) {
+		stderr = cmdErr.stderr
+	}
+	return strings.NewReplacer(
+		"{error}", err.Error(),
+		"{stderr}", strings.Join(stderr, " | "),
+	).Replace(m.ErrorFormat)
+}
+
+// blockEnv returns the i3blocks environment variables of the block.
+func (m *ExternalCmd) blockEnv(info gobar.BlockInfo, cm *gobar.ClickMessage) []string {
+	if !m.I3Blocks {
+		return nil
+	}
+	instance := m.Instance
+	if instance == "" {
+		instance = info.Instance
+	}
+	var button, x, y string
+	if cm != nil {
+		button = fmt.Sprint(cm.Button)
+		x, y = fmt.Sprint(cm.X), fmt.Sprint(cm.Y)
+	}
+	env := []string{
+		"BLOCK_NAME=" + info.Name,
+		"BLOCK_INSTANCE=" + instance,
+		"BLOCK_BUTTON=" + button,
+		"BLOCK_X=" + x,
+		"BLOCK_Y=" + y,
+	}
+	return env
+}
+
+// execCommand runs the command and sets its output in the info, it returns
+// errBusy when no command slot is free, the info keeps the last output then.
+func (m *ExternalCmd) execCommand(argv []string, env []string, info *gobar.BlockInfo, wait time.Duration) error {
+	out, err := m.run(argv, env, wait)
+	if err == errBusy {
+		m.Lock()
+		if m.last != nil {
+			name, instance := info.Name, info.Instance
+			*info = *m.last
+			info.Name, info.Instance = name, instance
 		}
-	case 3: // right click, join zoom
-		if m.ClickRight != "" {
-			m.execCommand(m.ClickRight, &info)
-			return &info, nil
+		m.Unlock()
+		return err
+	}
+	defer func() {
+		m.Lock()
+		last := *info
+		m.last = &last
+		m.Unlock()
+	}()
+	urgent := false
+	var exitErr *exec.ExitError
+	if m.I3Blocks && errors.As(err, &exitErr) && exitErr.ExitCode() == urgentExitCode {
+		urgent, err = true, nil
+	}
+	if err != nil {
+		info.ShortText = m.formatError(err)
+		info.FullText = info.ShortText
+		info.Failed = true
+		return nil
+	}
+	if m.Format == "json" {
+		if err := m.applyJSON(out, info); err != nil {
+			info.ShortText = m.formatError(err)
+			info.FullText = info.ShortText
+			info.Failed = true
 		}
-	case 4: // scroll up, decrease
-		if m.ScrollUp != "" {
-			m.execCommand(m.ScrollUp, &info)
-			return &info, nil
+		return nil
+	}
+	text := strings.TrimRight(string(out), "\n")
+	if !m.I3Blocks {
+		info.ShortText = text
+		info.FullText = text
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	info.FullText = lines[0]
+	info.ShortText = lines[0]
+	if len(lines) > 1 && lines[1] != "" {
+		info.ShortText = lines[1]
+	}
+	if len(lines) > 2 && lines[2] != "" {
+		info.TextColor = lines[2]
+	}
+	info.IsUrgent = urgent
+	return nil
+}
+
+// runPersistent keeps the exec command running, it is restarted with an
+// exponential backoff when it exits.
+func (m *ExternalCmd) runPersistent() {
+	backoff := persistMinBackoff
+	for {
+		started := time.Now()
+		err := m.runOnce()
+		if m.ctx.Err() != nil {
+			m.log.Debug("Persistent command closed")
+			return
+		}
+		if time.Since(started) > persistMaxBackoff {
+			backoff = persistMinBackoff
 		}
-	case 5: // scroll down, decrease
-		if m.ScrollDown != "" {
-			m.execCommand(m.ScrollDown, &info)
-			return &info, nil
+		m.log.Warnf("Persistent command exited: %v, restart in %s", err, backoff)
+		m.setLine(fmt.Sprintf("exited: %v", err))
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > persistMaxBackoff {
+			backoff = persistMaxBackoff
 		}
 	}
-	return nil, nil
 }
 
-func (m *ExternalCmd) execCommand(cmd string, info *gobar.BlockInfo) {
-	out, err := exec.Command("sh", "-c", cmd).Output()
+func (m *ExternalCmd) runOnce() error {
+	c := m.command(m.ctx, m.execArgv(), nil)
+	stdout, err := c.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := c.StderrPipe()
 	if err != nil {
-		info.ShortText = err.Error()
-		info.FullText = err.Error()
+		return err
+	}
+	stderrDone := make(chan struct{})
+	go func() {
+		defer close(stderrDone)
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			m.log.Warnf("Persistent command stderr: %s", scanner.Text())
+		}
+	}()
+	var stdin io.WriteCloser
+	if m.ClickStdin {
+		if stdin, err = c.StdinPipe(); err != nil {
+			return err
+		}
+	}
+	if err := c.Start(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	if stdin != nil {
+		clicks := make(chan []byte, clickQueueSize)
+		go writeClicks(stdin, clicks, done)
+		m.Lock()
+		m.clicks = clicks
+		m.Unlock()
+	}
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		m.setLine(scanner.Text())
+	}
+	m.Lock()
+	m.clicks = nil
+	m.Unlock()
+	close(done)
+	<-stderrDone
+	if err := c.Wait(); err != nil {
+		return err
+	}
+	return scanner.Err()
+}
+
+func (m *ExternalCmd) setLine(line string) {
+	m.Lock()
+	m.lastLine = line
+	refresh := m.refresh
+	m.Unlock()
+	if refresh != nil {
+		refresh()
+	}
+}
+
+// applyLine sets the block from one line of the persistent command, JSON
+// lines are merged over the current info.
+func (m *ExternalCmd) applyLine(line string, info *gobar.BlockInfo) {
+	if m.Format == "json" && strings.HasPrefix(line, "{") {
+		if err := m.applyJSON([]byte(line), info); err != nil {
+			m.log.Warnf("Invalid JSON line: %s", err)
+		}
 		return
 	}
-	text := strings.TrimRight(string(out), "\n")
-	info.ShortText = text
-	info.FullText = text
-	return
+	info.FullText = line
+	info.ShortText = line
+}
+
+// applyJSON merges a gobar.BlockInfo JSON object over the info, the short
+// text follows the full text when it is not set. The block identity can not
+// be changed by the command.
+func (m *ExternalCmd) applyJSON(data []byte, info *gobar.BlockInfo) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	newInfo := *info
+	if err := json.Unmarshal(data, &newInfo); err != nil {
+		return err
+	}
+	if _, ok := fields["short_text"]; !ok {
+		newInfo.ShortText = newInfo.FullText
+	}
+	newInfo.Name, newInfo.Instance = info.Name, info.Instance
+	*info = newInfo
+	return nil
+}
+
+// clickQueueSize is the number of clicks waiting for a persistent command
+// which does not read its stdin.
+const clickQueueSize = 16
+
+// writeClick queues the click for the persistent command, it never blocks
+// the block.
+func (m *ExternalCmd) writeClick(cm gobar.ClickMessage) error {
+	line, err := json.Marshal(cm)
+	if err != nil {
+		return err
+	}
+	m.Lock()
+	clicks := m.clicks
+	m.Unlock()
+	if clicks == nil {
+		return errors.New("persistent command is not running")
+	}
+	select {
+	case clicks <- append(line, '\n'):
+		return nil
+	default:
+		return errors.New("persistent command does not read the clicks")
+	}
+}
+
+// writeClicks writes the queued clicks to the stdin of the command until it
+// exits, a blocked write is released by the exit of the command.
+func writeClicks(stdin io.WriteCloser, clicks <-chan []byte, done <-chan struct{}) {
+	defer stdin.Close()
+	for {
+		select {
+		case <-done:
+			return
+		case line := <-clicks:
+			if _, err := stdin.Write(line); err != nil {
+				return
+			}
+		}
+	}
 }
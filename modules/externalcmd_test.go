@@ -0,0 +1,69 @@
+package modules
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/gobar"
+)
+
+func newTestExternalCmd(t *testing.T, config string) *ExternalCmd {
+	t.Helper()
+	m := &ExternalCmd{}
+	if err := m.InitModule(json.RawMessage(config), xlog.GetLogger()); err != nil {
+		t.Fatalf("InitModule: %s", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestExternalCmdI3Blocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		exec     string
+		fullText string
+		urgent   bool
+	}{
+		{"ok", `echo full; echo short`, "full", false},
+		{"urgent", `echo full; exit 33`, "full", true},
+		{"urgent with stderr", `echo full; echo warning >&2; exit 33`, "full", true},
+		{"failed with stderr", `echo failed >&2; exit 1`, "exit status 1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, _ := json.Marshal(map[string]interface{}{"exec": tt.exec, "i3blocks": true})
+			m := newTestExternalCmd(t, string(config))
+			info := m.UpdateInfo(gobar.BlockInfo{Name: "ExternalCmd", Instance: "test"})
+			if info.FullText != tt.fullText {
+				t.Errorf("full text: %q, expected: %q", info.FullText, tt.fullText)
+			}
+			if info.IsUrgent != tt.urgent {
+				t.Errorf("urgent: %v, expected: %v", info.IsUrgent, tt.urgent)
+			}
+		})
+	}
+}
+
+func TestExternalCmdClickWaitsForUpdate(t *testing.T) {
+	m := newTestExternalCmd(t, `{"exec":"sleep 0.3; echo update","click_left":"echo clicked"}`)
+	info := gobar.BlockInfo{Name: "ExternalCmd", Instance: "test"}
+	updated := make(chan struct{})
+	go func() {
+		defer close(updated)
+		m.UpdateInfo(info)
+	}()
+	for len(m.running) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	newInfo, err := m.HandleClick(gobar.ClickMessage{Button: 1}, info)
+	<-updated
+	if err != nil {
+		t.Fatalf("HandleClick: %s", err)
+	}
+	if newInfo == nil || newInfo.FullText != "clicked" {
+		t.Errorf("info: %+v, expected the click output", newInfo)
+	}
+}
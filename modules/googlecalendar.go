@@ -3,12 +3,12 @@ package modules
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
-	"os/exec"
 	"regexp"
 	"strings"
 	"sync"
@@ -21,6 +21,7 @@ import (
 	"google.golang.org/api/option"
 
 	"github.com/Ak-Army/i3barfeeder/gobar"
+	"github.com/Ak-Army/i3barfeeder/internal/notify"
 )
 
 func init() {
@@ -41,10 +42,12 @@ type event struct {
 
 type GCal struct {
 	gobar.ModuleInterface
-	SecretFile  string `json:"secretFile"`
-	TokenFile   string `json:"tokenFile"`
-	Email       string `json:"email"`
-	MeetingLink map[string]*struct {
+	SecretFile string `json:"secretFile"`
+	TokenFile  string `json:"tokenFile"`
+	Email      string `json:"email"`
+	// Send a notification this many minutes before a meeting starts
+	NotifyBefore int `json:"notifyBefore"`
+	MeetingLink  map[string]*struct {
 		Regex  string `json:"regex"`
 		Simple string `json:"simple"`
 		regex  *regexp.Regexp
@@ -57,6 +60,8 @@ type GCal struct {
 	currentEvent  *event
 	eventLock     sync.Mutex
 	leftClick     time.Time
+	// clicked events restored from the saved state
+	clicked map[string]bool
 }
 
 func (m *GCal) InitModule(config json.RawMessage, log xlog.Logger) error {
@@ -112,6 +117,7 @@ func (m *GCal) UpdateInfo(info gobar.BlockInfo) gobar.BlockInfo {
 		m.lastQuery = time.Now()
 		m.reloadEvents()
 	}
+	m.notifyUpcoming()
 	if m.currentEvent == nil {
 		info.ShortText = "No events"
 		info.FullText = "No upcoming events found."
@@ -135,28 +141,107 @@ func (m *GCal) reloadEvents() {
 		var evs []*event
 		for _, e := range gevents.Items {
 			ev := &event{Event: e}
+			ev.meetingLink = m.findMeetingLink(ev)
+			evs = append(evs, ev)
+		}
+		m.eventLock.Lock()
+		for _, ev := range evs {
+			ev.clicked = m.clicked[ev.Id]
 			for _, oe := range m.events {
-				if oe.Id == e.Id {
+				if oe.Id == ev.Id {
 					ev.clicked = oe.clicked
 				}
 			}
-			ev.meetingLink = m.findMeetingLink(ev)
-			evs = append(evs, ev)
 		}
 		m.events = evs
+		m.eventLock.Unlock()
+	}
+}
+
+type gcalState struct {
+	// IDs of the already joined events
+	Clicked []string `json:"clicked"`
+}
+
+func (m *GCal) SaveState() (json.RawMessage, error) {
+	m.eventLock.Lock()
+	defer m.eventLock.Unlock()
+	state := gcalState{Clicked: []string{}}
+	for _, e := range m.events {
+		if e.clicked {
+			state.Clicked = append(state.Clicked, e.Id)
+		}
 	}
+	return json.Marshal(state)
+}
+
+func (m *GCal) RestoreState(data json.RawMessage) error {
+	var state gcalState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	m.eventLock.Lock()
+	defer m.eventLock.Unlock()
+	m.clicked = map[string]bool{}
+	for _, id := range state.Clicked {
+		m.clicked[id] = true
+	}
+	for _, e := range m.events {
+		e.clicked = e.clicked || m.clicked[e.Id]
+	}
+	return nil
+}
+
+func (m *GCal) notifyUpcoming() {
+	if m.NotifyBefore <= 0 {
+		return
+	}
+	now := time.Now()
+	for _, e := range m.events {
+		startDateTime, err := time.Parse(time.RFC3339, e.Start.DateTime)
+		if err != nil || m.isDeclined(e) {
+			continue
+		}
+		until := startDateTime.Sub(now)
+		if until <= 0 || until > time.Duration(m.NotifyBefore)*time.Minute {
+			continue
+		}
+		gobar.Notify(notify.Notification{
+			Key:     "gcal:" + e.Id,
+			Summary: fmt.Sprintf("%s in %d min", e.Summary, int(until.Minutes()+1)),
+			Body:    fmt.Sprintf("%s %s", startDateTime.Format("15:04"), e.meetingLink),
+			Icon:    "x-office-calendar",
+			Urgency: notify.UrgencyNormal,
+		})
+	}
+}
+
+var gcalButtons = map[int]string{
+	1: "gcal.reload",  // left click
+	2: "gcal.current", // middle button, back to the current event
+	3: "gcal.join",    // right click, join the meeting
+	4: "gcal.next",    // scroll up
+	5: "gcal.prev",    // scroll down
 }
 
 func (m *GCal) HandleClick(cm gobar.ClickMessage, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
-	switch cm.Button {
-	case 1: // left click
+	return m.HandleAction(gcalButtons[cm.Button], info)
+}
+
+func (m *GCal) Actions() []string {
+	return []string{"gcal.join", "gcal.next", "gcal.prev", "gcal.current", "gcal.reload"}
+}
+
+func (m *GCal) HandleAction(name string, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
+	switch name {
+	case "gcal.reload":
 		m.leftClick = time.Now()
 		if time.Now().Sub(m.leftClick) <= time.Second {
 			m.reloadEvents()
 			m.leftClick = time.Now().Add(-time.Second * 10)
 		}
 		return &info, nil
-	case 2: // middle button
+	case "gcal.current":
 		m.eventLock.Lock()
 		m.currentEvent = nil
 		m.eventLock.Unlock()
@@ -164,23 +249,30 @@ func (m *GCal) HandleClick(cm gobar.ClickMessage, info gobar.BlockInfo) (*gobar.
 		m.showEvent(e, &info)
 
 		return &info, nil
-	case 3: // right click, join zoom
+	case "gcal.join":
 		m.eventLock.Lock()
 		e := m.currentEvent
 		m.eventLock.Unlock()
 		if e == nil {
 			e = m.getCurrentEvent()
 		}
+		if e == nil {
+			return nil, errors.New("no event to join")
+		}
 		meetingLink := m.findMeetingLink(e)
+		m.eventLock.Lock()
 		e.clicked = true
+		m.eventLock.Unlock()
 		if meetingLink != "" {
-			m.openURL(meetingLink)
+			if err := gobar.OpenURL(meetingLink); err != nil {
+				return clickError(info, err)
+			}
 		} else {
 			s, _ := json.Marshal(e)
 			m.log.Warnf("unable to find zoom link: %s", string(s))
 			m.log.Warnf("unable to find zoom link: %s", e.Description)
 		}
-	case 4: // scroll up, decrease
+	case "gcal.next":
 		m.eventLock.Lock()
 		e := m.currentEvent
 		m.eventLock.Unlock()
@@ -197,7 +289,7 @@ func (m *GCal) HandleClick(cm gobar.ClickMessage, info gobar.BlockInfo) (*gobar.
 				return &info, nil
 			}
 		}
-	case 5: // scroll down, decrease
+	case "gcal.prev":
 		m.eventLock.Lock()
 		e := m.currentEvent
 		m.eventLock.Unlock()
@@ -311,6 +403,9 @@ func (m *GCal) getCurrentEvent() *event {
 }
 
 func (m *GCal) showEvent(event *event, info *gobar.BlockInfo) {
+	if event == nil {
+		return
+	}
 	startDateTime, err := time.Parse(time.RFC3339, event.Start.DateTime)
 	if err != nil {
 		return
@@ -327,11 +422,16 @@ func (m *GCal) showEvent(event *event, info *gobar.BlockInfo) {
 	if t.After(startDateTime) {
 		info.TextColor = "#30b856"
 	}
-	if m.isAccepted(event) && !event.clicked && event.meetingLink != "" {
-		sub := t.Sub(startDateTime)
-		if sub > -1*time.Minute && sub < time.Minute {
-			event.clicked = true
-			m.openURL(event.meetingLink)
+	if sub := t.Sub(startDateTime); sub > -1*time.Minute && sub < time.Minute &&
+		m.isAccepted(event) && event.meetingLink != "" {
+		m.eventLock.Lock()
+		join := !event.clicked
+		event.clicked = true
+		m.eventLock.Unlock()
+		if join {
+			if err := gobar.OpenURL(event.meetingLink); err != nil {
+				m.log.Warnf("Unable to join %s: %s", event.Summary, err)
+			}
 		}
 	}
 
@@ -405,7 +505,9 @@ func (m *GCal) getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
 
 	config.RedirectURL = ts.URL
 	authURL := config.AuthCodeURL(randState)
-	go m.openURL(authURL)
+	if err := gobar.OpenURL(authURL); err != nil {
+		m.log.Warnf("Unable to open the browser: %s", err)
+	}
 	m.log.Info("Authorize this app at: %s", authURL)
 	code := <-ch
 	m.log.Infof("Got code: %s", code)
@@ -418,17 +520,6 @@ func (m *GCal) getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
 	return token
 }
 
-func (m *GCal) openURL(url string) {
-	try := []string{"xdg-open", "brave-browser", "google-chrome", "firefox", "open"}
-	for _, bin := range try {
-		err := exec.Command(bin, url).Run()
-		if err == nil {
-			return
-		}
-	}
-	m.log.Infof("Error opening URL in browser.")
-}
-
 func (m *GCal) tokenFromFile() (*oauth2.Token, error) {
 	f, err := os.Open(m.TokenFile)
 	if err != nil {
@@ -3,7 +3,6 @@ package modules
 import (
 	"encoding/json"
 	"fmt"
-	"os/exec"
 	"strings"
 
 	"github.com/Ak-Army/xlog"
@@ -14,39 +13,64 @@ import (
 func init() {
 	gobar.AddModule("MemInfo", func() gobar.ModuleInterface {
 		return &MemInfo{
-			barConfig: defaultBarConfig(),
+			barConfig:     defaultBarConfig(),
+			historyConfig: defaultHistoryConfig(),
+			sysFS:         defaultSysFS(),
 		}
 	})
 }
 
 type MemInfo struct {
 	gobar.ModuleInterface
-	barConfig barConfig
+	barConfig     barConfig
+	historyConfig historyConfig
+	history       history
+	sysFS         sysFS
+	metricValues
 }
 
 func (m *MemInfo) InitModule(config json.RawMessage, log xlog.Logger) error {
 	if config != nil {
-		return json.Unmarshal(config, &m.barConfig)
+		if err := json.Unmarshal(config, &m.barConfig); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(config, &m.sysFS); err != nil {
+			return err
+		}
+		return json.Unmarshal(config, &m.historyConfig)
 	}
 	return nil
 }
 
-func (m MemInfo) UpdateInfo(info gobar.BlockInfo) gobar.BlockInfo {
-	free, total := m.memInfo()
+func (m *MemInfo) UpdateInfo(info gobar.BlockInfo) gobar.BlockInfo {
+	free, total, err := m.memInfo()
+	if err != nil {
+		setError(&info, err)
+		return info
+	}
 	freePercent := 100 - 100*(free/total)
+	m.set("memory_used_percent", freePercent)
+	m.set("memory_used_bytes", total-free)
+	m.set("memory_total_bytes", total)
 	info.ShortText = fmt.Sprintf("%d %s", int(freePercent), "%")
-	info.FullText = makeBar(freePercent, m.barConfig)
+	if m.historyConfig.enabled() {
+		m.history.add(freePercent, m.historyConfig.HistorySize)
+		info.FullText = makeGraph(&m.history, 100, m.historyConfig)
+		if m.historyConfig.needsMarkup() {
+			info.Markup = gobar.MarkupPango
+		}
+		return info
+	}
+	setBar(&info, freePercent, m.barConfig)
 
 	return info
 }
 
-func (m MemInfo) HandleClick(cm gobar.ClickMessage, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
-	split := strings.Split("gnome-system-monitor -r", " ")
-
-	return nil, exec.Command(split[0], split[1:]...).Start()
+func (m *MemInfo) HandleClick(cm gobar.ClickMessage, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
+	return clickError(info, gobar.Launch("gnome-system-monitor", "-r"))
 }
 
-func (m MemInfo) memInfo() (float64, float64) {
+func (m *MemInfo) memInfo() (float64, float64, error) {
 	mem := map[string]float64{
 		"MemTotal": 0,
 		"MemFree":  0,
@@ -55,13 +79,15 @@ func (m MemInfo) memInfo() (float64, float64) {
 	}
 	callback := func(line string) bool {
 		fields := strings.Split(line, ":")
-		if _, ok := mem[fields[0]]; ok {
+		if _, ok := mem[fields[0]]; ok && len(fields) > 1 {
 			var val float64
 			fmt.Sscanf(fields[1], "%f", &val)
 			mem[fields[0]] = val * 1024
 		}
 		return true
 	}
-	readLines("/proc/meminfo", callback)
-	return mem["MemFree"] + mem["Buffers"] + mem["Cached"], mem["MemTotal"]
+	if err := m.sysFS.readLines("proc/meminfo", callback); err != nil {
+		return 0, 0, err
+	}
+	return mem["MemFree"] + mem["Buffers"] + mem["Cached"], mem["MemTotal"], nil
 }
@@ -4,10 +4,12 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
-	"os"
+	"html"
 	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Ak-Army/i3barfeeder/gobar"
 
@@ -19,17 +21,25 @@ func init() {
 		return &Network{
 			InterfaceName: []string{"tun1"},
 			barConfig:     defaultBarConfig(),
+			historyConfig: defaultHistoryConfig(),
+			sysFS:         defaultSysFS(),
 		}
 	})
 }
 
 type Network struct {
+	sync.Mutex
 	gobar.ModuleInterface
 	InterfaceName []string `json:"InterfaceName"`
 	barConfig     barConfig
+	historyConfig historyConfig
+	history       history
+	sysFS         sysFS
 	currRx        uint64
 	currTx        uint64
+	lastCollect   time.Time
 	log           xlog.Logger
+	metricValues
 }
 
 func (m *Network) InitModule(config json.RawMessage, log xlog.Logger) error {
@@ -41,26 +51,99 @@ func (m *Network) InitModule(config json.RawMessage, log xlog.Logger) error {
 		if err := json.Unmarshal(config, &m.barConfig); err != nil {
 			return err
 		}
+		if err := json.Unmarshal(config, &m.historyConfig); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(config, &m.sysFS); err != nil {
+			return err
+		}
 	}
-	_, m.currRx, m.currTx = m.collectData()
+	var err error
+	_, m.currRx, m.currTx, err = m.collectData()
+	if err != nil {
+		m.log.Warn("Unable to read network statistics", err)
+	}
+	m.lastCollect = time.Now()
 
 	return nil
 }
 
 func (m *Network) UpdateInfo(info gobar.BlockInfo) gobar.BlockInfo {
-	name, currRx, currTx := m.collectData()
-	info.ShortText = fmt.Sprintf("%s %s / %s", name, byteSize(currRx-m.currRx), byteSize(currTx-m.currTx))
-	info.FullText = fmt.Sprintf("%s %s / %s", name, byteSize(currRx-m.currRx), byteSize(currTx-m.currTx))
-	m.currRx, m.currTx = currRx, currTx
+	m.Lock()
+	defer m.Unlock()
+	name, currRx, currTx, err := m.collectData()
+	if err != nil {
+		setError(&info, err)
+		return info
+	}
+	rx, tx := counterDelta(currRx, m.currRx), counterDelta(currTx, m.currTx)
+	if elapsed := time.Since(m.lastCollect).Seconds(); elapsed > 0 {
+		m.set("network_receive_bytes_per_second", float64(rx)/elapsed)
+		m.set("network_transmit_bytes_per_second", float64(tx)/elapsed)
+	}
+	m.lastCollect = time.Now()
+	info.ShortText = fmt.Sprintf("%s %s / %s", name, byteSize(rx), byteSize(tx))
+	info.FullText = fmt.Sprintf("%s %s / %s", name, byteSize(rx), byteSize(tx))
+	if m.historyConfig.enabled() {
+		m.history.add(float64(rx+tx), m.historyConfig.HistorySize)
+		graph := makeGraph(&m.history, m.history.max(), m.historyConfig)
+		text := info.FullText
+		if m.historyConfig.needsMarkup() {
+			info.Markup = gobar.MarkupPango
+			text = html.EscapeString(text)
+		}
+		info.FullText = text + " " + graph
+		if m.historyConfig.HistoryRows > 1 {
+			// next to the bottom row of the chart
+			info.FullText = graph + " " + text
+		}
+	}
+	if name != "none" {
+		// the counters of a returning interface continue from the last ones
+		m.currRx, m.currTx = currRx, currTx
+	}
 	return info
 }
 
-func (m *Network) collectData() (string, uint64, uint64) {
+// counterDelta is the growth of a counter since the previous sample, a lower
+// counter was reset or its interface is gone.
+func counterDelta(curr uint64, prev uint64) uint64 {
+	if curr < prev {
+		return 0
+	}
+	return curr - prev
+}
+
+type networkState struct {
+	History []float64 `json:"history"`
+}
+
+func (m *Network) SaveState() (json.RawMessage, error) {
+	m.Lock()
+	defer m.Unlock()
+	return json.Marshal(networkState{
+		History: m.history.values,
+	})
+}
+
+func (m *Network) RestoreState(data json.RawMessage) error {
+	var state networkState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	m.Lock()
+	defer m.Unlock()
+	// only the history, the rate of the saved counters would cover the
+	// downtime
+	m.history.values = state.History
+	return nil
+}
+
+func (m *Network) collectData() (string, uint64, uint64, error) {
 	// Reference: man 5 proc, Documentation/filesystems/proc.txt in Linux source code
-	file, err := os.Open("/proc/net/dev")
+	file, err := m.sysFS.open("proc/net/dev")
 	if err != nil {
-		m.log.Warn("File open error", err)
-		return "none", 0, 0
+		return "none", 0, 0, err
 	}
 	defer file.Close()
 	scanner := bufio.NewScanner(file)
@@ -96,7 +179,7 @@ func (m *Network) collectData() (string, uint64, uint64) {
 		if err == nil {
 			ssids := strings.SplitN(string(out), "ESSID:\"", 2)
 			if len(ssids) < 2 {
-				return name, rxBytes, txBytes
+				return name, rxBytes, txBytes, nil
 			}
 			ssid := ssids[1]
 			ssid = strings.Split(ssid, "\"")[0]
@@ -104,15 +187,14 @@ func (m *Network) collectData() (string, uint64, uint64) {
 			sigLevel = strings.Split(sigLevel, " ")[0]
 			name = fmt.Sprintf("%s (%s dB)", ssid, sigLevel)
 		}
-		return name, rxBytes, txBytes
+		return name, rxBytes, txBytes, nil
 	}
 	if err := scanner.Err(); err != nil {
-		m.log.Warn("File scan error", err)
-		return "none", 0, 0
+		return "none", 0, 0, err
 	}
-	return "none", 0, 0
+	return "none", 0, 0, nil
 }
 
-func (m Network) HandleClick(cm gobar.ClickMessage, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
+func (m *Network) HandleClick(cm gobar.ClickMessage, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
 	return nil, nil
 }
@@ -0,0 +1,138 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/gobar"
+)
+
+// The testdata/sysfs tree is a sample of procfs and sysfs, testdata/sysfs-later
+// is the next sample of the counters.
+const (
+	sysFSRoot      = "testdata/sysfs"
+	sysFSLaterRoot = "testdata/sysfs-later"
+)
+
+func newSysFSModule(t *testing.T, name string, config string) gobar.ModuleInterface {
+	t.Helper()
+	module, err := gobar.NewModule(name, json.RawMessage(config), xlog.NopLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return module
+}
+
+func TestCpuInfo(t *testing.T) {
+	m := newSysFSModule(t, "CpuInfo", `{"root":"testdata/sysfs"}`).(*CpuInfo)
+	tests := []struct {
+		root  string
+		usage float64
+	}{
+		{sysFSRoot, 0}, // no previous sample
+		{sysFSLaterRoot, 25},
+		{sysFSLaterRoot, 0}, // unchanged counters
+	}
+	for i, test := range tests {
+		m.sysFS.Root = test.root
+		usage, err := m.CpuInfo()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if usage != test.usage {
+			t.Errorf("sample %d: usage %v, expected %v", i, usage, test.usage)
+		}
+	}
+}
+
+func TestMemInfo(t *testing.T) {
+	m := newSysFSModule(t, "MemInfo", `{"root":"testdata/sysfs"}`).(*MemInfo)
+	free, total, err := m.memInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if free != 8000000*1024 || total != 16000000*1024 {
+		t.Errorf("free %v, total %v", free, total)
+	}
+	if info := m.UpdateInfo(gobar.BlockInfo{}); info.ShortText != "50 %" {
+		t.Errorf("short text: %q", info.ShortText)
+	}
+}
+
+func TestBattery(t *testing.T) {
+	tests := []struct {
+		config string
+		text   string
+	}{
+		{`{"root":"testdata/sysfs"}`, "40 %"},
+		{`{"root":"testdata/sysfs","interfaceName":"BAT1"}`, "open testdata/sysfs/sys/class/power_supply/BAT1/energy_full: no such file or directory"},
+	}
+	for _, test := range tests {
+		m := newSysFSModule(t, "Battery", test.config).(*Battery)
+		if info := m.UpdateInfo(gobar.BlockInfo{}); info.ShortText != test.text {
+			t.Errorf("%s: short text %q, expected %q", test.config, info.ShortText, test.text)
+		}
+	}
+	m := newSysFSModule(t, "Battery", `{"root":"testdata/sysfs"}`).(*Battery)
+	if status := m.readStatus(); status != "Discharging" {
+		t.Errorf("status: %q", status)
+	}
+}
+
+func TestNetwork(t *testing.T) {
+	tests := []struct {
+		interfaces []string
+		root       string
+		name       string
+		rx, tx     uint64
+	}{
+		{[]string{"eth0"}, sysFSRoot, "eth0", 1000, 500},
+		{[]string{"wlan0", "eth0"}, sysFSLaterRoot, "eth0", 3048, 524},
+		{[]string{"lo"}, sysFSRoot, "lo", 4096, 4096},
+		{[]string{"wlan0"}, sysFSRoot, "none", 0, 0},
+	}
+	for _, test := range tests {
+		interfaces, _ := json.Marshal(test.interfaces)
+		config := fmt.Sprintf(`{"root":%q,"InterfaceName":%s}`, test.root, interfaces)
+		m := newSysFSModule(t, "Network", config).(*Network)
+		name, rx, tx, err := m.collectData()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != test.name || rx != test.rx || tx != test.tx {
+			t.Errorf("%s: %s %d %d, expected %s %d %d", config, name, rx, tx, test.name, test.rx, test.tx)
+		}
+	}
+
+	m := newSysFSModule(t, "Network", `{"root":"testdata/sysfs","InterfaceName":["eth0"]}`).(*Network)
+	m.sysFS.Root = sysFSLaterRoot
+	if info := m.UpdateInfo(gobar.BlockInfo{}); info.ShortText != "eth0 2.0 kB / 24 B" {
+		t.Errorf("short text: %q", info.ShortText)
+	}
+	// the counters were reset
+	m.sysFS.Root = sysFSRoot
+	if info := m.UpdateInfo(gobar.BlockInfo{}); info.ShortText != "eth0 0 B / 0 B" {
+		t.Errorf("short text after a reset: %q", info.ShortText)
+	}
+	// the interface is gone
+	m.InterfaceName = []string{"wlan0"}
+	m.historyConfig.HistorySize = 4
+	if info := m.UpdateInfo(gobar.BlockInfo{}); info.ShortText != "none 0 B / 0 B" {
+		t.Errorf("short text without the interface: %q", info.ShortText)
+	}
+	if max := m.history.max(); max != 0 {
+		t.Errorf("history max: %v", max)
+	}
+	if rate := m.Metrics()["network_receive_bytes_per_second"]; rate != 0 {
+		t.Errorf("receive rate: %v", rate)
+	}
+	// the interface is back
+	m.InterfaceName = []string{"eth0"}
+	m.sysFS.Root = sysFSLaterRoot
+	if info := m.UpdateInfo(gobar.BlockInfo{}); info.ShortText != "eth0 2.0 kB / 24 B" {
+		t.Errorf("short text of the returning interface: %q", info.ShortText)
+	}
+}
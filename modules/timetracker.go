@@ -0,0 +1,1133 @@
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ak-Army/timer"
+	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/gobar"
+	"github.com/Ak-Army/i3barfeeder/internal/clockify"
+	"github.com/Ak-Army/i3barfeeder/internal/filetracker"
+	"github.com/Ak-Army/i3barfeeder/internal/httpclient"
+	"github.com/Ak-Army/i3barfeeder/internal/notify"
+	"github.com/Ak-Army/i3barfeeder/internal/picker"
+	"github.com/Ak-Army/i3barfeeder/internal/state"
+	"github.com/Ak-Army/i3barfeeder/internal/tickets"
+	"github.com/Ak-Army/i3barfeeder/internal/toggl"
+	"github.com/Ak-Army/i3barfeeder/internal/tracker"
+	"github.com/Ak-Army/i3barfeeder/internal/worktime"
+)
+
+const (
+	secondsPerMinute = 60
+	secondsPerHour   = 60 * 60
+)
+
+func init() {
+	gobar.AddModule("TimeTracker", newTimeTracker(""))
+	gobar.AddModule("Toggl", newTimeTracker("toggl"))
+	gobar.AddModule("Clockify", newTimeTracker("clockify"))
+}
+
+// trackerRefresh is the interval of fetching the projects, the tickets and
+// the time worked today.
+const trackerRefresh = 5 * time.Minute
+
+func newTimeTracker(backend string) func() gobar.ModuleInterface {
+	return func() gobar.ModuleInterface {
+		return &TimeTracker{
+			Backend:       backend,
+			todayDuration: "00s",
+			PickerCache:   60,
+		}
+	}
+}
+
+// trackerBackends creates the backends of the time tracking block by name,
+// the name prefixes the actions and the metrics of the block.
+var trackerBackends = map[string]func(m *TimeTracker) (tracker.Tracker, error){
+	"toggl": func(m *TimeTracker) (tracker.Tracker, error) {
+		return toggl.NewTracker(m.ApiToken, m.DefaultWID, m.log), nil
+	},
+	"clockify": func(m *TimeTracker) (tracker.Tracker, error) {
+		return clockify.NewTracker(m.ApiToken, m.Workspace, m.log), nil
+	},
+	"local": func(m *TimeTracker) (tracker.Tracker, error) {
+		if m.File == "" {
+			m.File = filepath.Join(filepath.Dir(state.DefaultPath()), "timetracker.jsonl")
+		}
+		return filetracker.New(m.File, m.localProjects())
+	},
+}
+
+// trackerExporter is a backend able to export its entries for an other one.
+type trackerExporter interface {
+	Export(w io.Writer, format string, email string) error
+}
+
+type TimeTracker struct {
+	sync.Mutex
+	gobar.ModuleInterface
+	// toggl, clockify or local, the Toggl and Clockify modules set it
+	Backend  string `json:"backend"`
+	ApiToken string `json:"apiToken"`
+	// Toggl workspace of the started entries
+	DefaultWID int64 `json:"defaultWID"`
+	// Clockify workspace, the default one of the user when empty
+	Workspace string `json:"workspace"`
+	// JSON lines file of the local backend
+	File string `json:"file"`
+	// Task names by project name of the local backend, the projects of the
+	// tickets are added
+	LocalProjects map[string][]string `json:"localProjects"`
+	// toggl or clockify, the CSV import format of the export action
+	ExportFormat string `json:"exportFormat"`
+	// The CSV file written by the export action, next to File when empty
+	ExportFile string `json:"exportFile"`
+	// User of the exported entries
+	Email       string       `json:"email"`
+	TicketNames []ticketName `json:"ticketNames"`
+	// Send a notification when the timer is running longer than this hours
+	NotifyAfter float64 `json:"notifyAfter"`
+	// File of the actions waiting for the API while offline
+	QueueFile string `json:"queueFile"`
+	// Command of the project/task picker, rofi or dmenu when empty
+	Picker []string `json:"picker"`
+	// Minutes while the project tasks are not fetched again
+	PickerCache int    `json:"pickerCache"`
+	YtApiUrl    string `json:"ytApiUrl"`
+	YtApiToken  string `json:"ytApiToken"`
+	// YouTrack search of the listed issues, "for: me #Unresolved" when empty
+	YtQuery    string `json:"ytQuery"`
+	TpApiUrl   string `json:"tpApiUrl"`
+	TpApiToken string `json:"tpApiToken"`
+	// Post the time spent to YouTrack and TargetProcess when an entry stops
+	PostSpentTime bool `json:"postSpentTime"`
+	// Working hours, holidays and the start of the overtime balance
+	WorkTime worktime.Config `json:"workTime"`
+	// Show the overtime balance in the block
+	ShowBalance bool `json:"showBalance"`
+	// Format of the summary action: text, csv, markdown or tsv
+	SummaryFormat string `json:"summaryFormat"`
+	// Period of the summary action: month, week, last-week, this-month or
+	// last-month
+	SummaryPeriod    string `json:"summaryPeriod"`
+	calendar         *worktime.Calendar
+	balance          time.Duration
+	providers        []tickets.Provider
+	tracker          tracker.Tracker
+	queue            *tracker.Queue
+	tasks            map[tracker.ID]tracker.Tasks
+	tasksLoaded      time.Time
+	tickets          []ticket
+	currentTimeEntry tracker.Entry
+	updateTimeEntry  tracker.Entry
+	todayDuration    string
+	currentName      int
+	updateTimer      timer.Timer
+	ticker           timer.Ticker
+	done             chan struct{}
+	refresh          func()
+	log              xlog.Logger
+	projects         tracker.Projects
+	// an open picker and the error of the last one, shown by the next update
+	pickMu  sync.Mutex
+	picking bool
+	pickErr error
+	metricValues
+}
+
+type ticketName struct {
+	Name    string `json:"name"`
+	TPId    string `json:"tpId"`
+	Project string `json:"project"`
+	YtId    string `json:"ytId"`
+	// Project, task and TargetProcess id triples, one ticket per triple
+	Projects [][]string `json:"projects"`
+	Tags     []string   `json:"tags"`
+}
+type ticket struct {
+	name   string
+	PID    tracker.ID
+	TID    tracker.ID
+	tags   []string
+	source tickets.Ticket
+}
+
+func (m *TimeTracker) InitModule(config json.RawMessage, log xlog.Logger) error {
+	m.log = log
+	if err := json.Unmarshal(config, m); err != nil {
+		return err
+	}
+	newTracker, ok := trackerBackends[m.Backend]
+	if !ok {
+		return fmt.Errorf("unknown time tracker backend: `%s`", m.Backend)
+	}
+	var err error
+	if m.tracker, err = newTracker(m); err != nil {
+		return err
+	}
+	if m.calendar, err = worktime.New(m.WorkTime); err != nil {
+		return err
+	}
+	if m.YtApiUrl != "" {
+		m.providers = append(m.providers, tickets.NewYouTrack(m.YtApiUrl, m.YtApiToken, m.YtQuery, log))
+	}
+	if m.TpApiUrl != "" {
+		m.providers = append(m.providers, tickets.NewTargetProcess(m.TpApiUrl, m.TpApiToken, log))
+	}
+	if m.QueueFile == "" {
+		m.QueueFile = m.defaultQueueFile()
+	}
+	if m.queue, err = tracker.OpenQueue(m.QueueFile); err != nil {
+		return err
+	}
+	m.calcRemainingTime()
+	m.updateProjectsAndTasks()
+
+	done := make(chan struct{})
+	m.done = done
+	m.ticker = timer.NewTicker(m.Backend+"Ticker", 10*time.Second)
+	go func() {
+		lastRefresh := time.Now()
+		for {
+			select {
+			case <-done:
+				return
+			case <-m.ticker.C():
+			}
+			if m.queue.Len() > 0 {
+				m.replay()
+			}
+			m.Lock()
+			if m.updateTimeEntry.ID == "" && m.queue.Len() == 0 {
+				m.getCurrentTimeEntry()
+			}
+			refresh := time.Since(lastRefresh) >= trackerRefresh
+			if refresh {
+				lastRefresh = time.Now()
+				m.calcRemainingTime()
+			}
+			m.Unlock()
+			if refresh {
+				m.updateProjectsAndTasks()
+			}
+		}
+	}()
+	m.updateTimer = timer.NewTimer(m.Backend+"UpdateTimer", time.Second)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-m.updateTimer.C():
+				m.updateCurrentTimeEntry()
+			}
+		}
+	}()
+	return nil
+}
+
+// Close stops the timers and saves the queue, the block of the reloaded
+// configuration takes over the queue file.
+func (m *TimeTracker) Close() error {
+	m.Lock()
+	defer m.Unlock()
+	if m.done == nil {
+		return nil
+	}
+	m.ticker.Stop()
+	m.updateTimer.SafeStop()
+	close(m.done)
+	m.done = nil
+	return m.queue.Save()
+}
+
+func (m *TimeTracker) SetRefresh(refresh func()) {
+	m.pickMu.Lock()
+	defer m.pickMu.Unlock()
+	m.refresh = refresh
+}
+
+// runPicker runs a picker flow in the background, the clicks of the other
+// blocks do not wait for it. The block is updated when the flow ends.
+func (m *TimeTracker) runPicker(flow func() error) {
+	m.pickMu.Lock()
+	defer m.pickMu.Unlock()
+	if m.picking {
+		m.log.Debug("A picker is open already")
+		return
+	}
+	m.picking = true
+	go func() {
+		err := flow()
+		if err != nil {
+			m.log.Warnf("Picker: %s", err)
+		}
+		m.pickMu.Lock()
+		m.picking = false
+		m.pickErr = err
+		refresh := m.refresh
+		m.pickMu.Unlock()
+		if refresh != nil {
+			refresh()
+		}
+	}()
+}
+
+func (m *TimeTracker) UpdateInfo(info gobar.BlockInfo) gobar.BlockInfo {
+	m.pickMu.Lock()
+	err := m.pickErr
+	m.pickErr = nil
+	m.pickMu.Unlock()
+	if err != nil {
+		setError(&info, err)
+		return info
+	}
+	m.set(m.Backend+"_running_seconds", 0)
+	if m.currentTimeEntry.ID != "" {
+		running := m.currentTimeEntry.Duration().Seconds()
+		m.set(m.Backend+"_running_seconds", running)
+		prettyTime := fmt.Sprintf("%s / %s",
+			prettyPrintDuration(int(running), true),
+			m.todayDuration)
+		text := m.entryText(m.currentTimeEntry)
+		shortDesc := text
+		if len(text) > 7 {
+			shortDesc = text[0:7]
+		}
+		info.ShortText = fmt.Sprintf("%s - %s", shortDesc, prettyTime)
+		info.FullText = fmt.Sprintf("%s - %s", text, prettyTime)
+		if m.NotifyAfter > 0 && running > m.NotifyAfter*secondsPerHour {
+			gobar.Notify(notify.Notification{
+				Key:     fmt.Sprintf("%s:%s", m.Backend, m.currentTimeEntry.ID),
+				Summary: "Timer is still running",
+				Body:    fmt.Sprintf("%s - %s", text, prettyTime),
+				Icon:    "appointment-soon",
+				Urgency: notify.UrgencyNormal,
+			})
+		}
+	} else {
+		info.ShortText = fmt.Sprintf("%s", m.todayDuration)
+		info.FullText = fmt.Sprintf("%s", info.ShortText)
+	}
+	if m.ShowBalance {
+		balance := m.balance
+		if m.currentTimeEntry.ID != "" {
+			balance += m.currentTimeEntry.Duration()
+		}
+		sign := ""
+		if balance >= 0 {
+			sign = "+"
+		}
+		info.FullText += fmt.Sprintf(" (%s%s)", sign, worktime.FormatHours(balance))
+	}
+	if n := m.queue.Len(); n > 0 {
+		info.ShortText += fmt.Sprintf(" [%d]", n)
+		info.FullText += fmt.Sprintf(" [%d pending]", n)
+	}
+	return info
+}
+
+// pickChoice is a ticket, a project or a task of a project in the picker.
+type pickChoice struct {
+	label       string
+	description string
+	PID         tracker.ID
+	TID         tracker.ID
+	tags        []string
+}
+
+// loadTasks fetches the tasks of the active projects when the cached ones
+// are older than PickerCache minutes, they are cached only when every
+// project succeeded.
+func (m *TimeTracker) loadTasks(ctx context.Context) {
+	m.Lock()
+	if m.tasks != nil && time.Since(m.tasksLoaded) < time.Duration(m.PickerCache)*time.Minute {
+		m.Unlock()
+		return
+	}
+	projects := m.projects
+	m.Unlock()
+	tasks := map[tracker.ID]tracker.Tasks{}
+	failed := false
+	for _, p := range projects {
+		if !p.Active {
+			continue
+		}
+		projectTasks, err := m.tracker.Tasks(ctx, p)
+		if err != nil {
+			m.log.Errorf("Unable to get project tasks: %s %s: %s", p.ID, p.Name, err)
+			failed = true
+			continue
+		}
+		tasks[p.ID] = projectTasks
+	}
+	m.Lock()
+	defer m.Unlock()
+	if failed {
+		// keep the tasks of the failed projects, fetch them again next time
+		for id, projectTasks := range m.tasks {
+			if _, ok := tasks[id]; !ok {
+				tasks[id] = projectTasks
+			}
+		}
+	} else {
+		m.tasksLoaded = time.Now()
+	}
+	m.tasks = tasks
+	for _, p := range m.projects {
+		p.Tasks = m.tasks[p.ID]
+	}
+}
+
+func (m *TimeTracker) pickChoices() []pickChoice {
+	m.Lock()
+	defer m.Unlock()
+	var choices []pickChoice
+	for _, t := range m.tickets {
+		label := t.name
+		if proj := m.projects.FindById(t.PID); proj != nil {
+			label += " - " + proj.Name
+			if task := proj.Tasks.FindById(t.TID); task != nil {
+				label += " / " + task.Name
+			}
+		}
+		choices = append(choices, pickChoice{
+			label:       label,
+			description: t.name,
+			PID:         t.PID,
+			TID:         t.TID,
+			tags:        t.tags,
+		})
+	}
+	for _, p := range m.projects {
+		if !p.Active {
+			continue
+		}
+		choices = append(choices, pickChoice{label: p.Name, description: p.Name, PID: p.ID})
+		for _, t := range p.Tasks {
+			if t.Active {
+				choices = append(choices, pickChoice{
+					label:       p.Name + " / " + t.Name,
+					description: p.Name + " / " + t.Name,
+					PID:         p.ID,
+					TID:         t.ID,
+				})
+			}
+		}
+	}
+	return choices
+}
+
+// pick starts a new entry or switches the running one to the project or task
+// picked with the picker. The module is not locked while the picker is open.
+func (m *TimeTracker) pick() error {
+	ctx, cancel := apiContext()
+	m.loadTasks(ctx)
+	cancel()
+	choices := m.pickChoices()
+	if len(choices) == 0 {
+		return errors.New("no projects to pick from")
+	}
+	command, err := picker.Command(m.Picker, m.Backend)
+	if err != nil {
+		return err
+	}
+	labels := make([]string, len(choices))
+	for i, choice := range choices {
+		labels[i] = choice.label
+	}
+	pickCtx, cancelPick := pickContext()
+	i, err := picker.Pick(pickCtx, command, labels)
+	cancelPick()
+	if err == picker.ErrCancelled {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	choice := choices[i]
+
+	ctx, cancel = apiContext()
+	defer cancel()
+	m.Lock()
+	defer m.Unlock()
+	m.updateTimer.SafeStop()
+	m.updateTimeEntry = tracker.Entry{}
+	if m.currentTimeEntry.ID != "" {
+		entry := m.currentTimeEntry
+		entry.Description = choice.description
+		entry.ProjectID = choice.PID
+		entry.TaskID = choice.TID
+		entry.Tags = choice.tags
+		err = m.send(tracker.ActionUpdate, entry, func(entry tracker.Entry) error {
+			_, err := m.tracker.Update(ctx, entry)
+			return err
+		})
+		if err == nil {
+			m.currentTimeEntry = entry
+		}
+	} else {
+		err = m.start(ctx, tracker.Entry{
+			Description: choice.description,
+			ProjectID:   choice.PID,
+			TaskID:      choice.TID,
+			Tags:        choice.tags,
+			Start:       time.Now(),
+		})
+	}
+	return err
+}
+
+var trackerButtons = map[int]string{
+	1: "pick",    // left click, pick a project or task
+	2: "summary", // middle button, copy the month summary
+	3: "toggle",  // right click, start/stop
+	4: "next",    // scroll up
+	5: "prev",    // scroll down
+}
+
+// {"name":"Toggl","instance":"id_0","button":5,"x":2991,"y":12}
+func (m *TimeTracker) HandleClick(cm gobar.ClickMessage, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
+	name, ok := trackerButtons[cm.Button]
+	if ok {
+		name = m.Backend + "." + name
+	}
+	return m.HandleAction(name, info)
+}
+
+// Actions are prefixed with the backend, eg: toggl.toggle
+func (m *TimeTracker) Actions() []string {
+	var actions []string
+	for _, name := range []string{"toggle", "next", "prev", "summary", "report", "pick"} {
+		actions = append(actions, m.Backend+"."+name)
+	}
+	if _, ok := m.tracker.(trackerExporter); ok {
+		actions = append(actions, m.Backend+".export")
+	}
+	return actions
+}
+
+func (m *TimeTracker) HandleAction(name string, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
+	name = strings.TrimPrefix(name, m.Backend+".")
+	switch name {
+	case "pick":
+		m.runPicker(m.pick)
+		return nil, nil
+	case "export":
+		return clickError(info, m.export())
+	case "report":
+		m.runPicker(m.pickReport)
+		return nil, nil
+	}
+	ctx, cancel := apiContext()
+	defer cancel()
+	m.Lock()
+	defer m.Unlock()
+	if m.queue.Len() == 0 {
+		if current, err := m.tracker.Current(ctx); err == nil {
+			m.currentTimeEntry = current
+		}
+	}
+	m.updateTimer.SafeStop()
+	m.updateTimeEntry = tracker.Entry{}
+	switch name {
+	case "summary":
+		text, err := m.report(ctx, m.SummaryPeriod, m.SummaryFormat)
+		if err == nil {
+			err = gobar.Copy(text)
+		}
+		if err != nil {
+			setError(&info, err)
+			return &info, err
+		}
+	case "toggle":
+		if m.currentTimeEntry.ID != "" {
+			entry := m.currentTimeEntry
+			err := m.send(tracker.ActionStop, entry, func(entry tracker.Entry) error {
+				_, err := m.tracker.Stop(ctx, entry)
+				return err
+			})
+			if err != nil {
+				setError(&info, err)
+				return &info, err
+			}
+			m.postSpentTime(entry)
+			m.balance += entry.Duration()
+			m.currentTimeEntry = tracker.Entry{}
+			m.currentName = 0
+		} else {
+			t := m.ticket(0)
+			err := m.start(ctx, tracker.Entry{
+				Description: t.name,
+				ProjectID:   t.PID,
+				TaskID:      t.TID,
+				Tags:        t.tags,
+				Start:       time.Now(),
+			})
+			if err != nil {
+				setError(&info, err)
+				return &info, err
+			}
+		}
+	case "next", "prev":
+		if len(m.tickets) == 0 {
+			break
+		}
+		if name == "next" {
+			m.currentName = (m.currentName + 1) % len(m.tickets)
+		} else {
+			m.currentName = (m.currentName - 1 + len(m.tickets)) % len(m.tickets)
+		}
+		t := m.tickets[m.currentName]
+		m.currentTimeEntry.Description = t.name
+		m.currentTimeEntry.ProjectID = t.PID
+		m.currentTimeEntry.TaskID = t.TID
+		m.currentTimeEntry.Tags = t.tags
+		m.updateTimer.SafeReset(time.Second * 1)
+		m.updateTimeEntry = m.currentTimeEntry
+	}
+	info = m.UpdateInfo(info)
+	return &info, nil
+}
+
+// defaultQueueFile is a queue file per block, the queue file of the backend
+// used before is taken over.
+func (m *TimeTracker) defaultQueueFile() string {
+	dir := filepath.Dir(state.DefaultPath())
+	shared := filepath.Join(dir, m.Backend+"-queue.json")
+	instance := gobar.BlockInstance(m.log)
+	if instance == "" {
+		return shared
+	}
+	file := filepath.Join(dir, m.Backend+"-"+instance+"-queue.json")
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		if err := os.Rename(shared, file); err == nil {
+			m.log.Infof("Queue file moved: %s", file)
+		}
+	}
+	return file
+}
+
+// localProjects returns the configured projects of the local backend and
+// the projects and tasks of the tickets.
+func (m *TimeTracker) localProjects() map[string][]string {
+	projects := map[string][]string{}
+	add := func(project string, task string) {
+		if project == "" {
+			return
+		}
+		if _, ok := projects[project]; !ok {
+			projects[project] = nil
+		}
+		if task != "" && !hasString(projects[project], task) {
+			projects[project] = append(projects[project], task)
+		}
+	}
+	for project, tasks := range m.LocalProjects {
+		add(project, "")
+		for _, task := range tasks {
+			add(project, task)
+		}
+	}
+	for _, ticketName := range m.TicketNames {
+		add(ticketName.Project, "")
+		for _, triple := range ticketName.Projects {
+			if len(triple) == 3 {
+				add(triple[0], triple[1])
+			}
+		}
+	}
+	return projects
+}
+
+// export writes the entries of the backend to ExportFile in the CSV import
+// format of Toggl or Clockify.
+func (m *TimeTracker) export() error {
+	exporter, ok := m.tracker.(trackerExporter)
+	if !ok {
+		return fmt.Errorf("%s entries can not be exported", m.Backend)
+	}
+	format := m.ExportFormat
+	if format == "" {
+		format = filetracker.FormatToggl
+	}
+	path := m.ExportFile
+	if path == "" {
+		path = strings.TrimSuffix(m.File, filepath.Ext(m.File)) + "-" + format + ".csv"
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := exporter.Export(f, format, m.Email); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	m.log.Infof("Time entries exported to %s", path)
+	gobar.Notify(notify.Notification{
+		Summary: "Time entries exported",
+		Body:    path,
+		Icon:    "document-save",
+	})
+	return nil
+}
+
+// ticket returns the i-th ticket, an empty one when no tickets are configured.
+func (m *TimeTracker) ticket(i int) ticket {
+	if i < 0 || i >= len(m.tickets) {
+		return ticket{}
+	}
+	return m.tickets[i]
+}
+
+func (m *TimeTracker) start(ctx context.Context, entry tracker.Entry) error {
+	return m.send(tracker.ActionStart, entry, func(entry tracker.Entry) error {
+		started, err := m.tracker.Start(ctx, entry)
+		if err == nil {
+			m.currentTimeEntry = started
+		}
+		return err
+	})
+}
+
+// send calls the API, or queues the action when the API is unreachable or
+// older actions are still waiting. The entries started offline run locally
+// until they are replayed.
+func (m *TimeTracker) send(kind tracker.ActionKind, entry tracker.Entry, call func(entry tracker.Entry) error) error {
+	// the entry may have been replayed since it was read
+	entry.ID = m.queue.ResolveID(entry.ID)
+	if m.queue.Len() == 0 && !entry.ID.Local() {
+		err := call(entry)
+		if err == nil || !httpclient.Temporary(err) {
+			return err
+		}
+		m.log.Warnf("API is unreachable, queue %s: %s", kind, err)
+	}
+	if kind == tracker.ActionStart {
+		entry.ID = m.queue.LocalID()
+		m.currentTimeEntry = entry
+	}
+	return m.queue.Push(tracker.Action{
+		Kind:  kind,
+		Time:  time.Now(),
+		Entry: entry,
+	})
+}
+
+// replay sends the queued actions without locking the module, the clicks
+// queue their actions meanwhile.
+func (m *TimeTracker) replay() {
+	ctx, cancel := apiContext()
+	defer cancel()
+	if err := m.queue.Replay(ctx, m.tracker, m.log); err != nil {
+		m.log.Warnf("Unable to replay the queued actions: %s", err)
+	}
+	m.Lock()
+	defer m.Unlock()
+	m.currentTimeEntry.ID = m.queue.ResolveID(m.currentTimeEntry.ID)
+}
+
+type trackerState struct {
+	CurrentName int `json:"currentName"`
+}
+
+func (m *TimeTracker) SaveState() (json.RawMessage, error) {
+	m.Lock()
+	defer m.Unlock()
+	return json.Marshal(trackerState{CurrentName: m.currentName})
+}
+
+func (m *TimeTracker) RestoreState(data json.RawMessage) error {
+	var state trackerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	m.Lock()
+	defer m.Unlock()
+	if state.CurrentName >= 0 && state.CurrentName < len(m.tickets) {
+		m.currentName = state.CurrentName
+	}
+	return nil
+}
+
+func (m *TimeTracker) calcRemainingTime() {
+	ctx, cancel := apiContext()
+	defer cancel()
+	now := time.Now()
+	t := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	timeEntries, err := m.tracker.Entries(ctx, t, time.Time{})
+	m.log.Debugf("calcRemainingTime %+v", timeEntries, err)
+	m.todayDuration = "00s"
+	if err == nil {
+		var dur float64
+		for _, timeEntry := range timeEntries {
+			dur += timeEntry.Duration().Seconds()
+		}
+		if int(dur) > 0 {
+			m.todayDuration = prettyPrintDuration(int(dur), false)
+		}
+		m.set(m.Backend+"_today_seconds", dur)
+	}
+	if m.ShowBalance {
+		m.calcBalance(ctx)
+	}
+}
+
+// calcBalance sums the overtime of the stopped entries from the start of
+// the balance, today counts with the worked time only. The running entry is
+// added while it is shown.
+func (m *TimeTracker) calcBalance(ctx context.Context) {
+	now := time.Now()
+	from := m.calendar.BalanceFrom(now)
+	entries, err := m.tracker.Entries(ctx, from, now)
+	if err != nil {
+		m.log.Error("Unable to calculate the balance", err)
+		return
+	}
+	m.balance = m.calendar.BalanceUntil(from, now, workedByDay(entries))
+	m.set(m.Backend+"_balance_seconds", m.balance.Seconds())
+}
+
+// report returns the worked and the expected time of the days of a period,
+// see worktime.Period and worktime.Write.
+func (m *TimeTracker) report(ctx context.Context, period string, format string) (string, error) {
+	from, to, err := worktime.Period(period, time.Now())
+	if err != nil {
+		return "", err
+	}
+	entries, err := m.tracker.Entries(ctx, from, to)
+	if err != nil {
+		return "", err
+	}
+	var text strings.Builder
+	err = worktime.Write(&text, format, m.calendar.Days(from, to, workedByDay(entries)))
+	return text.String(), err
+}
+
+// pickReport copies the report of the period and in the format picked with
+// the picker. The module is not locked while the picker is open.
+func (m *TimeTracker) pickReport() error {
+	var picked []string
+	for _, choice := range []struct {
+		prompt string
+		items  []string
+	}{{"period", worktime.Periods}, {"format", worktime.Formats}} {
+		command, err := picker.Command(m.Picker, choice.prompt)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := pickContext()
+		i, err := picker.Pick(ctx, command, choice.items)
+		cancel()
+		if err == picker.ErrCancelled {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		picked = append(picked, choice.items[i])
+	}
+	ctx, cancel := apiContext()
+	defer cancel()
+	text, err := m.report(ctx, picked[0], picked[1])
+	if err == nil {
+		err = gobar.Copy(text)
+	}
+	return err
+}
+
+// workedByDay sums the stopped entries by their day, eg: 2006-01-02
+func workedByDay(entries []tracker.Entry) map[string]time.Duration {
+	worked := map[string]time.Duration{}
+	for _, entry := range entries {
+		if entry.Stop == nil {
+			continue
+		}
+		worked[entry.Start.Local().Format("2006-01-02")] += entry.Duration()
+	}
+	return worked
+}
+
+func (m *TimeTracker) getCurrentTimeEntry() {
+	ctx, cancel := apiContext()
+	defer cancel()
+	var err error
+	currentTimeEntry, err := m.tracker.Current(ctx)
+	if err != nil {
+		m.log.Error("getCurrentTimeEntry", err)
+		return
+	}
+	if m.updateTimeEntry.ID != "" {
+		return
+	}
+	m.currentTimeEntry = currentTimeEntry
+	if pid := currentTimeEntry.ProjectID; pid != "" && m.projects.FindById(pid) == nil {
+		m.log.Error("Project not found", pid)
+	}
+}
+
+// entryText is the shown text of an entry: the description with the name of
+// its project and task. The entries keep their own description, this text is
+// never sent to the tracker.
+func (m *TimeTracker) entryText(entry tracker.Entry) string {
+	text := entry.Description
+	if len(text) > 50 {
+		text = text[0:50] + "..."
+	}
+	if entry.ProjectID == "" {
+		return text
+	}
+	proj := m.projects.FindById(entry.ProjectID)
+	if proj == nil {
+		return text
+	}
+	task := proj.Tasks.FindById(entry.TaskID)
+	if task == nil {
+		return text + fmt.Sprintf(" - %s", proj.Name)
+	}
+	return text + fmt.Sprintf(" - %s / %s", proj.Name, task.Name)
+}
+
+func (m *TimeTracker) updateCurrentTimeEntry() {
+	ctx, cancel := apiContext()
+	defer cancel()
+	m.Lock()
+	defer m.Unlock()
+	id := m.updateTimeEntry.ID
+	if id == "" {
+		return
+	}
+	m.log.Info("Update", m.updateTimeEntry)
+	entry := m.updateTimeEntry
+	err := m.send(tracker.ActionUpdate, entry, func(entry tracker.Entry) error {
+		_, err := m.tracker.Update(ctx, entry)
+		return err
+	})
+	if err != nil {
+		return
+	}
+	if id == m.updateTimeEntry.ID {
+		m.updateTimeEntry = tracker.Entry{}
+	} else {
+		m.updateTimer.SafeReset(time.Second * 1)
+	}
+}
+
+// updateProjectsAndTasks fetches the projects and the tickets without
+// holding the lock, every request has its own timeout.
+func (m *TimeTracker) updateProjectsAndTasks() {
+	ctx, cancel := apiContext()
+	projects, err := m.tracker.Projects(ctx)
+	cancel()
+	m.Lock()
+	if err != nil {
+		m.log.Error("Unable to get workspace projects", err)
+		// copies, the shown projects are not changed without the lock
+		projects = nil
+		for _, p := range m.projects {
+			project := *p
+			projects = append(projects, &project)
+		}
+	}
+	tasks := make(map[tracker.ID]tracker.Tasks, len(m.tasks))
+	for id, projectTasks := range m.tasks {
+		tasks[id] = projectTasks
+	}
+	m.Unlock()
+	for _, p := range projects {
+		p.Tasks = tasks[p.ID]
+	}
+	var tickets []ticket
+	for _, ticketName := range m.TicketNames {
+		if len(ticketName.Projects) == 0 {
+			t, err := m.newTicket(projects, tasks, ticketName, ticketName.TPId, ticketName.Project, "")
+			if err != nil {
+				m.log.Error(err)
+				continue
+			}
+			tickets = append(tickets, t)
+		}
+		for _, triple := range ticketName.Projects {
+			if len(triple) != 3 {
+				m.log.Errorf("Invalid project of %s, expected [project, task, tpId]: %v", ticketName.Name, triple)
+				continue
+			}
+			t, err := m.newTicket(projects, tasks, ticketName, triple[2], triple[0], triple[1])
+			if err != nil {
+				m.log.Error(err)
+				continue
+			}
+			tickets = append(tickets, t)
+		}
+	}
+	tickets = append(tickets, m.providerTickets(tickets)...)
+	m.Lock()
+	defer m.Unlock()
+	if m.tasks == nil {
+		m.tasks = map[tracker.ID]tracker.Tasks{}
+	}
+	for id, projectTasks := range tasks {
+		if _, ok := m.tasks[id]; !ok {
+			m.tasks[id] = projectTasks
+		}
+	}
+	for _, p := range projects {
+		p.Tasks = m.tasks[p.ID]
+	}
+	m.projects = projects
+	if len(tickets) > 0 {
+		m.tickets = tickets
+	}
+}
+
+// newTicket maps a ticket to its project and task by their names, the tasks
+// of the project are fetched into tasks when they are not loaded yet.
+func (m *TimeTracker) newTicket(projects tracker.Projects, tasks map[tracker.ID]tracker.Tasks, ticketName ticketName, tpID string, project string, task string) (ticket, error) {
+	t := ticket{
+		name: strings.TrimSpace(fmt.Sprintf("%s %s", tpID, ticketName.Name)),
+		tags: ticketName.Tags,
+		source: tickets.Ticket{
+			Name: ticketName.Name,
+			YTID: ticketName.YtId,
+			TPID: tpID,
+		},
+	}
+	if project == "" {
+		return t, nil
+	}
+	proj := projects.FindByName(project)
+	if proj == nil {
+		return t, fmt.Errorf("Project not found: %s", project)
+	}
+	t.PID = proj.ID
+	if task == "" {
+		return t, nil
+	}
+	if proj.Tasks == nil {
+		ctx, cancel := apiContext()
+		projectTasks, err := m.tracker.Tasks(ctx, proj)
+		cancel()
+		if err != nil {
+			return t, fmt.Errorf("Unable to get project tasks: %s: %s", project, err)
+		}
+		tasks[proj.ID] = projectTasks
+		proj.Tasks = projectTasks
+	}
+	if found := proj.Tasks.FindByName(task); found != nil {
+		t.TID = found.ID
+	} else {
+		m.log.Errorf("Task not found: %s / %s", project, task)
+	}
+	return t, nil
+}
+
+// providerTickets returns the issues of the ticket providers which are not
+// configured yet, they are tracked without a project.
+func (m *TimeTracker) providerTickets(configured []ticket) []ticket {
+	known := map[string]bool{"": true}
+	for _, t := range configured {
+		known[t.source.YTID] = true
+		known[t.source.TPID] = true
+	}
+	var list []ticket
+	for _, provider := range m.providers {
+		ctx, cancel := apiContext()
+		issues, err := provider.Tickets(ctx)
+		cancel()
+		if err != nil {
+			m.log.Errorf("Unable to get %s tickets: %s", provider.Name(), err)
+			continue
+		}
+		for _, issue := range issues {
+			id := issue.YTID + issue.TPID
+			if known[id] {
+				continue
+			}
+			known[id] = true
+			list = append(list, ticket{
+				name:   fmt.Sprintf("%s %s", id, issue.Name),
+				source: issue,
+			})
+		}
+	}
+	return list
+}
+
+// ticketOf finds the ticket of a time entry by its description, project and
+// task.
+func (m *TimeTracker) ticketOf(entry tracker.Entry) *ticket {
+	var found *ticket
+	for i, t := range m.tickets {
+		if !strings.HasPrefix(entry.Description, t.name) || (t.PID != "" && t.PID != entry.ProjectID) {
+			continue
+		}
+		if t.TID == entry.TaskID {
+			return &m.tickets[i]
+		}
+		if found == nil {
+			found = &m.tickets[i]
+		}
+	}
+	return found
+}
+
+// postSpentTime posts the duration of a stopped entry to the ticket
+// providers in the background.
+func (m *TimeTracker) postSpentTime(entry tracker.Entry) {
+	if !m.PostSpentTime || len(m.providers) == 0 {
+		return
+	}
+	t := m.ticketOf(entry)
+	if t == nil {
+		m.log.Debugf("No ticket of the entry: %s", entry.Description)
+		return
+	}
+	source := t.source
+	spent := entry.Duration()
+	go func() {
+		for _, provider := range m.providers {
+			ctx, cancel := apiContext()
+			err := provider.LogTime(ctx, source, entry.Start, spent, entry.Description)
+			cancel()
+			if err != nil {
+				m.log.Errorf("Unable to post the spent time to %s: %s", provider.Name(), err)
+			}
+		}
+	}()
+}
+
+func prettyPrintDuration(sec int, withSec bool) string {
+	var hour, min int
+	hour = sec / secondsPerHour
+	sec -= hour * secondsPerHour
+	min = sec / secondsPerMinute
+	sec -= min * secondsPerMinute
+
+	returnString := ""
+	if hour > 0 {
+		returnString = fmt.Sprintf("%s%02dH ", returnString, hour)
+	}
+	if min > 0 {
+		returnString = fmt.Sprintf("%s%02dm ", returnString, min)
+	}
+	if sec > 0 && withSec {
+		returnString = fmt.Sprintf("%s%02ds", returnString, sec)
+	}
+
+	return returnString
+}
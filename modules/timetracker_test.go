@@ -0,0 +1,59 @@
+package modules
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ak-Army/xlog"
+
+	"github.com/Ak-Army/i3barfeeder/gobar"
+)
+
+func TestTimeTrackerPickInBackground(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", dir)
+	opened := filepath.Join(dir, "opened")
+	config, _ := json.Marshal(map[string]interface{}{
+		"backend":       "local",
+		"file":          filepath.Join(dir, "entries.jsonl"),
+		"localProjects": map[string][]string{"work": {"dev"}},
+		// an open picker picking an unknown item after a while
+		"picker": []string{"sh", "-c", "echo >> " + opened + "; sleep 0.3; echo unknown"},
+	})
+	m := &TimeTracker{Backend: "local", todayDuration: "00s", PickerCache: 60}
+	if err := m.InitModule(config, xlog.NopLogger); err != nil {
+		t.Fatalf("InitModule: %s", err)
+	}
+	defer m.Close()
+	refreshed := make(chan struct{}, 1)
+	m.SetRefresh(func() { refreshed <- struct{}{} })
+	info := gobar.BlockInfo{Name: "TimeTracker", Instance: "test"}
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if newInfo, err := m.HandleClick(gobar.ClickMessage{Button: 1}, info); newInfo != nil || err != nil {
+			t.Fatalf("click: %+v %v", newInfo, err)
+		}
+	}
+	if d := time.Since(start); d > 200*time.Millisecond {
+		t.Errorf("the clicks waited %s for the picker", d)
+	}
+	select {
+	case <-refreshed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the block was not refreshed")
+	}
+	if data, _ := os.ReadFile(opened); strings.Count(string(data), "\n") != 1 {
+		t.Errorf("the picker was opened %d times, expected once", strings.Count(string(data), "\n"))
+	}
+	if got := m.UpdateInfo(info); !strings.Contains(got.FullText, "unknown item picked") {
+		t.Errorf("full text: %q, expected the error of the picker", got.FullText)
+	}
+	if got := m.UpdateInfo(info); got.FullText != "00s" {
+		t.Errorf("full text: %q, expected the error to be gone", got.FullText)
+	}
+}
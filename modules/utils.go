@@ -3,16 +3,29 @@ package modules
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"sort"
+	"sync"
+	"time"
+
+	"github.com/Ak-Army/i3barfeeder/gobar"
 )
 
 type barConfig struct {
 	BarSize  int    `json:"barSize"`
 	BarFull  string `json:"barFull"`
 	BarEmpty string `json:"barEmpty"`
+	// Glyphs of partially filled cells from the least to the most filled,
+	// eg: ["▏", "▎", "▍", "▌", "▋", "▊", "▉"]
+	BarPartial []string `json:"barPartial"`
+	// Colors from low to high, the filled cells are colored with pango when set
+	BarGradient []string `json:"barGradient"`
+	// Draw the bar from the middle, negative values fill the left half
+	BarCentered bool `json:"barCentered"`
 }
 
 func defaultBarConfig() barConfig {
@@ -23,35 +36,303 @@ func defaultBarConfig() barConfig {
 	}
 }
 
-func makeBar(freePercent float64, barConfig barConfig) string {
+func (c barConfig) needsMarkup() bool {
+	return len(c.BarGradient) > 0
+}
+
+// makeBar draws a bar for a value between 0 and 100, or between -100 and 100
+// in centered mode.
+func makeBar(percent float64, barConfig barConfig) string {
+	if barConfig.BarSize < 0 {
+		barConfig.BarSize = 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	if !barConfig.BarCentered {
+		if percent < 0 {
+			percent = 0
+		}
+		cells := make([]string, barConfig.BarSize)
+		fillCells(cells, percent*.01*float64(barConfig.BarSize), barConfig)
+		return colorCells(cells, 0, 1, barConfig)
+	}
+	if percent < -100 {
+		percent = -100
+	}
+	half := barConfig.BarSize / 2
+	left := make([]string, half)
+	right := make([]string, barConfig.BarSize-half)
+	if percent < 0 {
+		fillCells(left, -percent*.01*float64(half), barConfig)
+		fillCells(right, 0, barConfig)
+	} else {
+		fillCells(left, 0, barConfig)
+		fillCells(right, percent*.01*float64(len(right)), barConfig)
+	}
+	// the left half grows from the middle, partial glyphs only grow to the
+	// right so they are replaced with full ones there
+	for i, j := 0, len(left)-1; i < j; i, j = i+1, j-1 {
+		left[i], left[j] = left[j], left[i]
+	}
+	for i, cell := range left {
+		if cell != barConfig.BarEmpty {
+			left[i] = barConfig.BarFull
+		}
+	}
+	return colorCells(left, len(left)-1, -1, barConfig) + colorCells(right, 0, 1, barConfig)
+}
+
+// setBar draws the bar into the full text and switches the block to pango
+// markup when the bar needs it.
+func setBar(info *gobar.BlockInfo, percent float64, barConfig barConfig) {
+	info.FullText = makeBar(percent, barConfig)
+	if barConfig.needsMarkup() {
+		info.Markup = gobar.MarkupPango
+	}
+}
+
+func fillCells(cells []string, fill float64, barConfig barConfig) {
+	full := int(fill)
+	for i := range cells {
+		switch {
+		case i < full:
+			cells[i] = barConfig.BarFull
+		case i == full && len(barConfig.BarPartial) > 0:
+			cells[i] = barConfig.BarEmpty
+			part := int((fill - float64(full)) * float64(len(barConfig.BarPartial)+1))
+			if part > 0 {
+				cells[i] = barConfig.BarPartial[part-1]
+			}
+		default:
+			cells[i] = barConfig.BarEmpty
+		}
+	}
+}
+
+// colorCells joins the cells, coloring the filled ones by their distance
+// from the start cell when a gradient is configured.
+func colorCells(cells []string, start int, dir int, barConfig barConfig) string {
 	var bar bytes.Buffer
-	cutoff := int(freePercent * .01 * float64(barConfig.BarSize))
-	for i := 0; i < barConfig.BarSize; i += 1 {
-		if i < cutoff {
-			bar.WriteString(barConfig.BarFull)
-		} else {
-			bar.WriteString(barConfig.BarEmpty)
+	for i, cell := range cells {
+		if !barConfig.needsMarkup() || cell == barConfig.BarEmpty {
+			bar.WriteString(cell)
+			continue
 		}
+		pos := float64((i - start) * dir)
+		color := barConfig.BarGradient[scaleSample(pos+1, float64(len(cells)), len(barConfig.BarGradient)-1)]
+		bar.WriteString(fmt.Sprintf(`<span foreground="%s">%s</span>`, color, cell))
 	}
 	return bar.String()
 }
 
-func readLines(fileName string, callback func(string) bool) {
-	fin, err := os.Open(fileName)
+type historyConfig struct {
+	// Number of samples kept, 0 disables the history graph
+	HistorySize int `json:"historySize"`
+	// "sparkline", one character of 8 levels per sample, or "braille", one
+	// character of 4 dot levels per two samples in every row
+	HistoryStyle string `json:"historyStyle"`
+	// Rows of the braille chart, the bar has to be tall enough for them
+	HistoryRows int `json:"historyRows"`
+	// Colors from low to high, every sample is colored with pango when set
+	HistoryColors []string `json:"historyColors"`
+}
+
+func defaultHistoryConfig() historyConfig {
+	return historyConfig{
+		HistoryStyle: "sparkline",
+		HistoryRows:  1,
+	}
+}
+
+func (c historyConfig) enabled() bool {
+	return c.HistorySize > 0
+}
+
+func (c historyConfig) needsMarkup() bool {
+	return c.enabled() && len(c.HistoryColors) > 0
+}
+
+// history is a fixed size rolling list of samples, oldest first.
+type history struct {
+	values []float64
+}
+
+func (h *history) add(value float64, size int) {
+	h.values = append(h.values, value)
+	if len(h.values) > size {
+		h.values = h.values[len(h.values)-size:]
+	}
+}
+
+func (h *history) max() float64 {
+	var max float64
+	for _, v := range h.values {
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+var sparkChars = []rune("▁▂▃▄▅▆▇█")
+
+// braille dots of the left and right column, from bottom to top
+var brailleDots = [2][4]rune{
+	{0x40, 0x04, 0x02, 0x01},
+	{0x80, 0x20, 0x10, 0x08},
+}
+
+// makeGraph renders the history with the configured style, values are
+// scaled between 0 and max. Samples missing from a not yet full history are
+// drawn as empty. The rows of a braille chart are separated by new lines,
+// the top row first.
+func makeGraph(h *history, max float64, config historyConfig) string {
+	samples := h.values
+	if len(samples) > config.HistorySize {
+		samples = samples[len(samples)-config.HistorySize:]
+	}
+	values := make([]float64, config.HistorySize-len(samples), config.HistorySize)
+	values = append(values, samples...)
+	var graph bytes.Buffer
+	switch config.HistoryStyle {
+	case "braille":
+		if len(values)%2 == 1 {
+			values = append([]float64{0}, values...)
+		}
+		rows := config.HistoryRows
+		if rows < 1 {
+			rows = 1
+		}
+		for row := rows - 1; row >= 0; row-- {
+			for i := 0; i < len(values); i += 2 {
+				char := rune(0x2800)
+				for col := 0; col < 2; col++ {
+					level := scaleSample(values[i+col], max, 4*rows) - 4*row
+					for dot := 0; dot < level && dot < 4; dot++ {
+						char |= brailleDots[col][dot]
+					}
+				}
+				peak := values[i]
+				if values[i+1] > peak {
+					peak = values[i+1]
+				}
+				graph.WriteString(colorSample(string(char), peak, max, config.HistoryColors))
+			}
+			if row > 0 {
+				graph.WriteString("\n")
+			}
+		}
+	default:
+		for _, v := range values {
+			level := scaleSample(v, max, len(sparkChars)-1)
+			graph.WriteString(colorSample(string(sparkChars[level]), v, max, config.HistoryColors))
+		}
+	}
+	return graph.String()
+}
+
+func scaleSample(value float64, max float64, levels int) int {
+	if max <= 0 || value <= 0 {
+		return 0
+	}
+	level := int(value / max * float64(levels))
+	if level > levels {
+		return levels
+	}
+	return level
+}
+
+func colorSample(text string, value float64, max float64, colors []string) string {
+	if len(colors) == 0 {
+		return text
+	}
+	color := colors[scaleSample(value, max, len(colors)-1)]
+	return fmt.Sprintf(`<span foreground="%s">%s</span>`, color, text)
+}
+
+// metricValues keeps the last values computed by a module for the metrics
+// endpoint.
+type metricValues struct {
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func (mv *metricValues) set(name string, value float64) {
+	mv.mu.Lock()
+	defer mv.mu.Unlock()
+	if mv.values == nil {
+		mv.values = make(map[string]float64)
+	}
+	mv.values[name] = value
+}
+
+func (mv *metricValues) Metrics() map[string]float64 {
+	mv.mu.Lock()
+	defer mv.mu.Unlock()
+	values := make(map[string]float64, len(mv.values))
+	for name, value := range mv.values {
+		values[name] = value
+	}
+	return values
+}
+
+// sysFS reads the procfs and sysfs files below a configurable root, so the
+// modules can read a host's /proc from a container or a fixture tree.
+type sysFS struct {
+	Root string `json:"root"`
+}
+
+func defaultSysFS() sysFS {
+	root := os.Getenv("I3BARFEEDER_ROOT")
+	if root == "" {
+		root = "/"
+	}
+	return sysFS{Root: root}
+}
+
+func (fs sysFS) open(name string) (*os.File, error) {
+	return os.Open(filepath.Join(fs.Root, name))
+}
+
+func (fs sysFS) readLines(name string, callback func(string) bool) error {
+	fin, err := fs.open(name)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "The file %s does not exist!\n", fileName)
-		return
+		return err
 	}
 	defer fin.Close()
 
 	reader := bufio.NewReader(fin)
-	for line, _, err := reader.ReadLine(); err != io.EOF; line, _, err = reader.ReadLine() {
+	for {
+		line, _, err := reader.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
 		if !callback(string(line)) {
-			break
+			return nil
 		}
 	}
 }
 
+func setError(info *gobar.BlockInfo, err error) {
+	info.ShortText = err.Error()
+	info.FullText = err.Error()
+	info.TextColor = "#FF2222"
+	info.Failed = true
+}
+
+// clickError shows the error of a click in the block until its next update.
+func clickError(info gobar.BlockInfo, err error) (*gobar.BlockInfo, error) {
+	if err == nil {
+		return nil, nil
+	}
+	setError(&info, err)
+	return &info, err
+}
+
 func byteSize(b uint64) string {
 	const unit = 1024
 	if b < unit {
@@ -101,3 +382,27 @@ func sortedKeys(m map[string]int64) []string {
 	sort.Sort(sm)
 	return sm.s
 }
+
+func hasString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// apiTimeout limits the API calls of one update or click, retries included,
+// so a hung request does not block the module.
+const apiTimeout = 15 * time.Second
+
+func apiContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), apiTimeout)
+}
+
+// pickTimeout closes a forgotten picker.
+const pickTimeout = 2 * time.Minute
+
+func pickContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), pickTimeout)
+}
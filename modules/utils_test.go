@@ -0,0 +1,57 @@
+package modules
+
+import "testing"
+
+func TestMakeBar(t *testing.T) {
+	tests := []struct {
+		name    string
+		percent float64
+		config  barConfig
+		bar     string
+	}{
+		{"empty", 0, barConfig{BarSize: 4, BarFull: "#", BarEmpty: "."}, "...."},
+		{"half", 50, barConfig{BarSize: 4, BarFull: "#", BarEmpty: "."}, "##.."},
+		{"over full", 150, barConfig{BarSize: 4, BarFull: "#", BarEmpty: "."}, "####"},
+		{"partial", 75, barConfig{BarSize: 2, BarFull: "#", BarEmpty: ".", BarPartial: []string{"-"}}, "#-"},
+		{"centered negative", -50, barConfig{BarSize: 4, BarFull: "#", BarEmpty: ".", BarCentered: true}, ".#.."},
+		{"centered positive", 100, barConfig{BarSize: 4, BarFull: "#", BarEmpty: ".", BarCentered: true}, "..##"},
+		{"zero size", 50, barConfig{BarSize: 0, BarFull: "#", BarEmpty: "."}, ""},
+		{"negative size", 50, barConfig{BarSize: -3, BarFull: "#", BarEmpty: "."}, ""},
+		{"negative size centered", 50, barConfig{BarSize: -3, BarFull: "#", BarEmpty: ".", BarCentered: true}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if bar := makeBar(tt.percent, tt.config); bar != tt.bar {
+				t.Errorf("bar: %q, expected: %q", bar, tt.bar)
+			}
+		})
+	}
+}
+
+func TestMakeGraph(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		config historyConfig
+		graph  string
+	}{
+		{"sparkline", []float64{0, 4, 8}, historyConfig{HistorySize: 3}, "▁▄█"},
+		{"sparkline not full", []float64{8}, historyConfig{HistorySize: 3}, "▁▁█"},
+		{"braille", []float64{0, 8, 4, 8}, historyConfig{HistorySize: 4, HistoryStyle: "braille", HistoryRows: 1}, "⢸⣼"},
+		{"braille odd size", []float64{8}, historyConfig{HistorySize: 1, HistoryStyle: "braille"}, "⢸"},
+		{"braille rows", []float64{0, 8, 4, 2}, historyConfig{HistorySize: 4, HistoryStyle: "braille", HistoryRows: 2}, "⢸⠀\n⢸⣧"},
+		{"braille colors", []float64{0, 8}, historyConfig{HistorySize: 2, HistoryStyle: "braille", HistoryRows: 2, HistoryColors: []string{"#0f0", "#f00"}},
+			`<span foreground="#f00">⢸</span>` + "\n" + `<span foreground="#f00">⢸</span>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &history{}
+			for _, v := range tt.values {
+				h.add(v, tt.config.HistorySize)
+			}
+			if graph := makeGraph(h, 8, tt.config); graph != tt.graph {
+				t.Errorf("graph: %q, expected: %q", graph, tt.graph)
+			}
+		})
+	}
+}
@@ -66,34 +66,49 @@ func (m *VolumeInfo) UpdateInfo(info gobar.BlockInfo) gobar.BlockInfo {
 			currentVolume -= 99
 			info.TextColor = "#FF2222"
 		}
-		info.FullText = makeBar(currentVolume, m.barConfig)
+		setBar(&info, currentVolume, m.barConfig)
 	}
 
 	if err != nil {
 		info.FullText = err.Error()
 		info.TextColor = "#FF2222"
+		info.Failed = true
 	}
 
 	return info
 }
 
+var volumeButtons = map[int]string{
+	3: "volume.mute", // right click, mute/unmute
+	4: "volume.up",   // scroll up, increase
+	5: "volume.down", // scroll down, decrease
+}
+
 // {"name":"VolumeInfo","instance":"id_1","button":5,"x":2991,"y":12}
 func (m *VolumeInfo) HandleClick(cm gobar.ClickMessage, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
+	return m.HandleAction(volumeButtons[cm.Button], info)
+}
+
+func (m *VolumeInfo) Actions() []string {
+	return []string{"volume.mute", "volume.up", "volume.down"}
+}
+
+func (m *VolumeInfo) HandleAction(name string, info gobar.BlockInfo) (*gobar.BlockInfo, error) {
 	var cmd string
-	switch cm.Button {
-	case 3: // right click, mute/unmute
+	switch name {
+	case "volume.mute":
 		cmd = `pactl set-sink-mute ` + m.card + ` toggle`
-	case 4: // scroll up, increase
+	case "volume.up":
 		cmd = `pactl set-sink-mute ` + m.card + ` false; pactl set-sink-volume ` + m.card + ` +5%`
-	case 5: // scroll down, decrease
+	case "volume.down":
 		cmd = `pactl set-sink-mute ` + m.card + ` false; pactl set-sink-volume ` + m.card + ` -5%`
 	}
 	m.log.Info(cmd)
 	if cmd != "" {
-		_, err := exec.Command("sh", "-c", cmd).Output()
-		if err == nil {
-			m.UpdateInfo(info)
+		if _, err := exec.Command("sh", "-c", cmd).Output(); err != nil {
+			return nil, err
 		}
+		info = m.UpdateInfo(info)
 	}
 	return &info, nil
 }